@@ -0,0 +1,77 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"strings"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// AliasObserver is told of a call of a method received on the subjects of an
+// alias, the former subject of a service
+type AliasObserver func(alias, method string)
+
+// AliasSubject returns the subject of a service from its fully qualified
+// proto name, such as pkg.Service, and its subject_prefix option
+func AliasSubject(prefix, fullName string) string {
+	subject := fullName
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		subject = fullName[:i] + "/" + fullName[i+1:]
+	}
+	if prefix != "" {
+		subject = prefix + "/" + subject
+	}
+	return subject
+}
+
+// WithAlias returns a handler telling the observers of the calls handled
+// on the subjects of an alias
+func WithAlias(alias, method string, handler nats.MsgHandler, observers ...AliasObserver) nats.MsgHandler {
+	return func(m *nats.Msg) {
+		for _, observer := range observers {
+			if observer != nil {
+				observer(alias, method)
+			}
+		}
+		handler(m)
+	}
+}
+
+// WithDurableAlias is WithAlias for the handlers of the durable methods
+func WithDurableAlias(alias, method string, handler DurableHandler, observers ...AliasObserver) DurableHandler {
+	return func(m *nats.Msg) error {
+		for _, observer := range observers {
+			if observer != nil {
+				observer(alias, method)
+			}
+		}
+		return handler(m)
+	}
+}
+
+// ObserveAlias counts the calls received on an alias in the statistics of
+// the endpoint. It does nothing on a nil registration.
+func (r *Registration) ObserveAlias(alias, method string) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.aliases[method] == nil {
+		r.aliases[method] = make(map[string]int)
+	}
+	r.aliases[method][alias]++
+}
@@ -0,0 +1,211 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	nats "github.com/nats-io/nats.go"
+	"google.golang.org/grpc/codes"
+)
+
+// HeaderAuthorization carries the credential of the caller, such as
+// Bearer <token> or ApiKey <key>
+const HeaderAuthorization = "Toldata-Authorization"
+
+// Schemes of the credentials
+const (
+	SchemeBearer = "Bearer"
+	SchemeAPIKey = "ApiKey"
+)
+
+var (
+	// ErrUnsupportedCredential is returned by the verifiers for the schemes
+	// they do not handle
+	ErrUnsupportedCredential = errors.New("unsupported credential")
+	// ErrMissingCredential is returned to the callers without credential
+	ErrMissingCredential = &Error{Message: "missing credential", Code: codes.Unauthenticated}
+)
+
+// Credential is the credential of a caller
+type Credential struct {
+	// Scheme is Bearer for the JWTs and ApiKey for the API keys
+	Scheme string
+	Value  string
+}
+
+// ParseCredential parses an Authorization header, e.g. Bearer <token>.
+// The scheme is matched case-insensitively.
+func ParseCredential(authorization string) (Credential, bool) {
+	parts := strings.SplitN(strings.TrimSpace(authorization), " ", 2)
+	if len(parts) != 2 {
+		return Credential{}, false
+	}
+	value := strings.TrimSpace(parts[1])
+	if value == "" {
+		return Credential{}, false
+	}
+	for _, scheme := range []string{SchemeBearer, SchemeAPIKey} {
+		if strings.EqualFold(parts[0], scheme) {
+			return Credential{Scheme: scheme, Value: value}, true
+		}
+	}
+	return Credential{Scheme: parts[0], Value: value}, true
+}
+
+// String returns the credential as an Authorization header
+func (c Credential) String() string {
+	return c.Scheme + " " + c.Value
+}
+
+type credentialKey struct{}
+
+// WithCredential returns a context carrying the credential. Calls made with
+// the context send it along to the servers.
+func WithCredential(ctx context.Context, credential Credential) context.Context {
+	return context.WithValue(ctx, credentialKey{}, credential)
+}
+
+// WithBearerToken returns a context whose calls send the token, a JWT
+func WithBearerToken(ctx context.Context, token string) context.Context {
+	return WithCredential(ctx, Credential{Scheme: SchemeBearer, Value: token})
+}
+
+// WithAPIKey returns a context whose calls send the API key
+func WithAPIKey(ctx context.Context, key string) context.Context {
+	return WithCredential(ctx, Credential{Scheme: SchemeAPIKey, Value: key})
+}
+
+// WithoutCredential returns a context whose calls send no credential, not
+// even Configuration.Credential
+func WithoutCredential(ctx context.Context) context.Context {
+	return WithCredential(ctx, Credential{})
+}
+
+// WithAuthorization returns a context whose calls send the credential of an
+// Authorization header. The gateways relay the calls with it, so a request
+// without credential is sent without any instead of the one of the gateway.
+func WithAuthorization(ctx context.Context, authorization string) context.Context {
+	if credential, ok := ParseCredential(authorization); ok {
+		return WithCredential(ctx, credential)
+	}
+	return WithoutCredential(ctx)
+}
+
+// CredentialFromContext returns the credential sent by the calls made with ctx
+func CredentialFromContext(ctx context.Context) (Credential, bool) {
+	credential, ok := ctx.Value(credentialKey{}).(Credential)
+	return credential, ok && credential.Value != ""
+}
+
+// setCredentialHeader sets the credential of ctx, the fallback unless ctx
+// has one or WithoutCredential
+func setCredentialHeader(ctx context.Context, header nats.Header, fallback Credential) {
+	credential, ok := ctx.Value(credentialKey{}).(Credential)
+	if !ok {
+		credential = fallback
+	}
+	if credential.Value != "" {
+		header.Set(HeaderAuthorization, credential.String())
+	}
+}
+
+// Principal is the authenticated caller of a method
+type Principal struct {
+	// Subject identifies the caller, the sub claim of the JWTs
+	Subject string
+	// Scheme is the scheme of the verified credential
+	Scheme string
+	// Claims are the claims of the JWT or the ones given to the API key
+	Claims map[string]interface{}
+}
+
+type principalKey struct{}
+
+// NewPrincipalContext returns a context carrying the principal
+func NewPrincipalContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller of the current call
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// Verifier verifies the credentials of the callers
+type Verifier interface {
+	// Verify returns the principal of a valid credential, and
+	// ErrUnsupportedCredential for the schemes it does not handle
+	Verify(ctx context.Context, credential Credential) (*Principal, error)
+}
+
+// Verifiers verifies the credentials with the first verifier supporting them
+type Verifiers []Verifier
+
+// Verify implements Verifier
+func (v Verifiers) Verify(ctx context.Context, credential Credential) (*Principal, error) {
+	for _, verifier := range v {
+		principal, err := verifier.Verify(ctx, credential)
+		if !errors.Is(err, ErrUnsupportedCredential) {
+			return principal, err
+		}
+	}
+	return nil, ErrUnsupportedCredential
+}
+
+// APIKeys verifies the API keys, mapped to their principal
+type APIKeys map[string]Principal
+
+// Verify implements Verifier
+func (keys APIKeys) Verify(ctx context.Context, credential Credential) (*Principal, error) {
+	if credential.Scheme != SchemeAPIKey {
+		return nil, ErrUnsupportedCredential
+	}
+	for key, principal := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(credential.Value)) == 1 {
+			principal.Scheme = SchemeAPIKey
+			return &principal, nil
+		}
+	}
+	return nil, errors.New("unknown API key")
+}
+
+// Authenticate returns the context of a call received by a server, with the
+// principal of its credential when Configuration.Verifier is set. Calls
+// without a valid credential are rejected, unless they have none and
+// Configuration.AllowAnonymous is set.
+func (bus *Bus) Authenticate(m *nats.Msg) (context.Context, error) {
+	ctx := bus.CallContext(m)
+	verifier := bus.Configuration.Verifier
+	if verifier == nil {
+		return ctx, nil
+	}
+
+	credential, ok := CredentialFromContext(ctx)
+	if !ok {
+		if bus.Configuration.AllowAnonymous {
+			return ctx, nil
+		}
+		return nil, ErrMissingCredential
+	}
+	principal, err := verifier.Verify(ctx, credential)
+	if err != nil {
+		return nil, &Error{Message: "invalid credential: " + err.Error(), Code: codes.Unauthenticated, cause: err}
+	}
+	return NewPrincipalContext(ctx, principal), nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Requirement is the access declared on a method with the scopes and roles
+// options
+type Requirement struct {
+	// Method is the gRPC name of the method, such as pkg.Service/Method
+	Method string
+	// Scopes must all be granted to the caller
+	Scopes []string
+	// Roles are the roles allowed to call the method, any caller when empty
+	Roles []string
+}
+
+// Policy decides whether a caller may call a method
+type Policy interface {
+	// Authorize returns an error when the principal, nil for the anonymous
+	// callers, may not call the method
+	Authorize(ctx context.Context, principal *Principal, requirement Requirement) error
+}
+
+// PolicyFunc is a Policy implemented by a function
+type PolicyFunc func(ctx context.Context, principal *Principal, requirement Requirement) error
+
+// Authorize implements Policy
+func (f PolicyFunc) Authorize(ctx context.Context, principal *Principal, requirement Requirement) error {
+	return f(ctx, principal, requirement)
+}
+
+// DefaultPolicy grants the methods to the callers with all their scopes and
+// one of their roles
+var DefaultPolicy Policy = PolicyFunc(func(ctx context.Context, principal *Principal, requirement Requirement) error {
+	if len(requirement.Scopes) == 0 && len(requirement.Roles) == 0 {
+		return nil
+	}
+	if principal == nil {
+		return errors.New("anonymous caller")
+	}
+	for _, scope := range requirement.Scopes {
+		if !contains(principal.Scopes(), scope) {
+			return errors.New("missing scope " + scope)
+		}
+	}
+	if len(requirement.Roles) == 0 {
+		return nil
+	}
+	for _, role := range requirement.Roles {
+		if contains(principal.Roles(), role) {
+			return nil
+		}
+	}
+	return errors.New("missing role")
+})
+
+// Denial describes a call refused by the policy
+type Denial struct {
+	Requirement Requirement
+	// Principal is the caller, nil for the anonymous callers
+	Principal *Principal
+	// Peer is the original caller of a call relayed by a gateway
+	Peer *Peer
+	// Reason is the error returned by the policy
+	Reason error
+}
+
+// PermissionDeniedError is returned to the callers refused by the policy
+func PermissionDeniedError(method string, reason error) error {
+	return &Error{Message: method + ": permission denied: " + reason.Error(), Code: codes.PermissionDenied, cause: reason}
+}
+
+// Authorize checks the access to a method of the caller of ctx with
+// Configuration.Policy, DefaultPolicy by default. The refused calls are
+// reported to Configuration.OnDenied.
+func (bus *Bus) Authorize(ctx context.Context, requirement Requirement) error {
+	policy := bus.Configuration.Policy
+	if policy == nil {
+		policy = DefaultPolicy
+	}
+	principal, _ := PrincipalFromContext(ctx)
+	reason := policy.Authorize(ctx, principal, requirement)
+	if reason == nil {
+		return nil
+	}
+
+	if bus.Configuration.OnDenied != nil {
+		p, _ := PeerFromContext(ctx)
+		bus.Configuration.OnDenied(ctx, Denial{
+			Requirement: requirement,
+			Principal:   principal,
+			Peer:        p,
+			Reason:      reason,
+		})
+	}
+	return PermissionDeniedError(requirement.Method, reason)
+}
+
+// Scopes returns the scopes granted to the principal, from the scope claim
+// separated by spaces or the scp claim
+func (p *Principal) Scopes() []string {
+	if scope, ok := p.Claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	return claimStrings(p.Claims["scp"])
+}
+
+// Roles returns the roles of the principal, from the roles claim
+func (p *Principal) Roles() []string {
+	return claimStrings(p.Claims["roles"])
+}
+
+func claimStrings(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []string:
+		return value
+	case []interface{}:
+		var result []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gogo/protobuf/proto"
+	nats "github.com/nats-io/nats.go"
+	"google.golang.org/grpc/codes"
+)
+
+// decodeReply returns the payload of a successful reply or the error it carries
+func decodeReply(msg *nats.Msg) ([]byte, error) {
+	if len(msg.Data) == 0 {
+		return nil, &Error{Message: "empty-reply", Code: codes.Internal}
+	}
+
+	if msg.Data[0] == 0 {
+		// 0 means no error
+		return msg.Data[1:], nil
+	}
+
+	var pErr ErrorMessage
+	err := proto.Unmarshal(msg.Data[1:], &pErr)
+	if err != nil {
+		return nil, err
+	}
+	return nil, pErr.Err()
+}
+
+// Call sends an encoded request to a method subject, e.g.
+// cdl.toldatatest/TestService/GetTestA, and returns the encoded response.
+// It is meant for callers without generated clients such as dynamic gateways.
+func (bus *Bus) Call(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	result, err := bus.Request(ctx, subject, data)
+	if err != nil {
+		return nil, RequestError(subject, err)
+	}
+	return decodeReply(result)
+}
+
+// CallIdempotent is Call for idempotent methods, the request is sent again
+// while no server is available
+func (bus *Bus) CallIdempotent(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	result, err := bus.RequestIdempotent(ctx, subject, data)
+	if err != nil {
+		return nil, RequestError(subject, err)
+	}
+	return decodeReply(result)
+}
+
+// RawStream is a streaming call made with encoded messages
+type RawStream struct {
+	Context context.Context
+	Bus     *Bus
+	Subject string
+	ID      string
+}
+
+// OpenStream starts a streaming call on a method subject. The encoded request
+// is given for server streams and is nil for client streams.
+func (bus *Bus) OpenStream(ctx context.Context, subject string, data []byte) (*RawStream, error) {
+	raw, err := bus.Call(ctx, subject, data)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &StreamInfo{}
+	err = proto.Unmarshal(raw, info)
+	if err != nil {
+		return nil, err
+	}
+	if info.ID == "" {
+		return nil, errors.New("invalid-stream-info")
+	}
+
+	return &RawStream{
+		Context: ctx,
+		Bus:     bus,
+		Subject: subject,
+		ID:      info.ID,
+	}, nil
+}
+
+// Send sends an encoded message on a client stream
+func (s *RawStream) Send(data []byte) error {
+	_, err := s.Bus.Call(s.Context, s.Subject+"_Send_"+s.ID, data)
+	return err
+}
+
+// Receive returns the next encoded message of a server stream,
+// io.EOF is returned at the end of the stream
+func (s *RawStream) Receive() ([]byte, error) {
+	return s.Bus.Call(s.Context, s.Subject+"_Receive_"+s.ID, nil)
+}
+
+// Done closes a client stream and returns the encoded response
+func (s *RawStream) Done() ([]byte, error) {
+	return s.Bus.Call(s.Context, s.Subject+"_Done_"+s.ID, nil)
+}
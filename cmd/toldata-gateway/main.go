@@ -0,0 +1,55 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command toldata-gateway exposes toldata services over gRPC and REST,
+// driven by descriptor sets instead of generated code.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/citradigital/toldata/gateway"
+)
+
+func main() {
+	configPath := flag.String("config", "toldata-gateway.json", "path of the configuration file")
+	flag.Parse()
+
+	config, err := gateway.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	g, err := gateway.New(ctx, *config)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer g.Close()
+
+	for _, service := range g.Services {
+		log.Println("Exposing", service.FullName())
+	}
+
+	err = g.Run(ctx)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -17,10 +17,10 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"go/format"
 	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
 	"strings"
 	"text/template"
 
@@ -30,56 +30,6 @@ import (
 	"github.com/gogo/protobuf/proto"
 )
 
-func getServiceOption(options *descriptor.ServiceOptions, index int) string {
-	descs, err := proto.ExtensionDescs(options)
-	if err == nil {
-		for _, desc := range descs {
-			if desc.Field == int32(index) {
-				ext, err := proto.GetExtension(options, desc)
-				if err == nil {
-					bytes, ok := ext.([]byte)
-					if ok {
-						op, len := proto.DecodeVarint(bytes)
-						tag := op >> 3
-						wire := op & 7
-
-						if wire == 2 && tag == uint64(index) {
-							strlen := int(bytes[len])
-							val := string(bytes[len+1 : strlen+len+1])
-							return val
-						}
-						break
-					}
-				}
-				break
-			}
-		}
-	}
-	return "/api"
-}
-
-func stripLastDot(name, packageName string) string {
-	if packageName != "" && strings.HasPrefix(name, "."+packageName) {
-		pos := strings.LastIndex(name, ".")
-		if pos == -1 {
-			return name
-		}
-		return name[pos+1:]
-	}
-
-	pos := strings.LastIndex(name, ".")
-	if pos == -1 {
-		return name
-	}
-	lpos := pos
-	pos = strings.LastIndex(name[:lpos], ".")
-	if pos == -1 {
-		return name[lpos+pos+1:]
-	}
-
-	return name[pos+1:]
-}
-
 func stringPtr(in string) *string {
 	if in == "" {
 		return nil
@@ -88,114 +38,140 @@ func stringPtr(in string) *string {
 	return &in
 }
 
-func newTemplate(content string) (*template.Template, error) {
+func newTemplate(name, content string, im *imports) (*template.Template, error) {
 	fn := map[string]interface{}{
-		"stripLastDot":     stripLastDot,
-		"getServiceOption": getServiceOption,
+		"goType":  im.goType,
+		"imports": im.lines,
+		"pkg":     im.pkg,
+		"runtime": im.runtimeType,
 	}
 
-	return template.New("page").Funcs(fn).Parse(content)
+	return template.New(name).Funcs(fn).Parse(content)
 }
 
-func generateBase(in *descriptor.FileDescriptorProto, outputFormat, templateString string) (*plugin_go.CodeGeneratorResponse_File, error) {
-	topPackageName := in.GetPackage()
-	packageName := in.Options.GetGoPackage()
-	if packageName == "" {
-		return nil, errors.New("Unable to find go_package options in .proto file")
+func generateBase(in *descriptor.FileDescriptorProto, params *parameters, index typeIndex, out output) (*plugin_go.CodeGeneratorResponse_File, error) {
+	importPath, packageName, err := params.goPackage(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetPackage() == "" {
+		return nil, errors.New("Unable to find package declaration in " + in.GetName())
+	}
+
+	err = validateOptions(in)
+	if err != nil {
+		return nil, err
 	}
 
-	if topPackageName == "" {
-		return nil, errors.New("Unable to find package declaration in .proto file")
+	im := newImports(index, importPath, params.runtimePackage())
+	t, err := newTemplate(out.name, out.template, im)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %v", out.name, err)
 	}
+	data := newFile(in, params, importPath, packageName, im)
 
+	// The imports are known once the types are resolved, the first pass only collects them
 	buf := bytes.NewBuffer(nil)
-	t, err := newTemplate(templateString)
+	err = t.Execute(buf, data)
+	if err == nil && im.err == nil {
+		buf.Reset()
+		err = t.Execute(buf, data)
+	}
 	if err != nil {
 		return nil, err
 	}
-	err = t.Execute(buf, map[string]interface{}{
-		"File":        *in.Name,
-		"PackageName": packageName,
-		"Services":    in.Service,
-		"Namespace":   topPackageName,
-	})
+	if im.err != nil {
+		return nil, fmt.Errorf("%s: %v", in.GetName(), im.err)
+	}
+
+	name := data.Base + out.suffix
+	if filename := t.Lookup("filename"); filename != nil {
+		nameBuf := bytes.NewBuffer(nil)
+		err = filename.Execute(nameBuf, data)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.TrimSpace(nameBuf.String())
+	}
+	filename, err := params.outputName(in, importPath, name)
 	if err != nil {
-		log.Println(err)
 		return nil, err
 	}
 
-	filename := *in.Name
-	ext := filepath.Ext(filename)
-	filename = filename[0 : len(filename)-len(ext)]
-	filename = fmt.Sprintf(outputFormat, filename)
+	content, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
 
 	return &plugin_go.CodeGeneratorResponse_File{
 		Name:    &filename,
-		Content: stringPtr(buf.String()),
+		Content: stringPtr(string(content)),
 	}, nil
-
 }
 
-func generate(in *descriptor.FileDescriptorProto) (*plugin_go.CodeGeneratorResponse_File, error) {
-	return generateBase(in, "%v.toldata.pb.go", rpcTemplate)
-}
-
-func generateGRPC(in *descriptor.FileDescriptorProto) (*plugin_go.CodeGeneratorResponse_File, error) {
-	return generateBase(in, "%v.grpc.pb.go", grpcTemplate)
-}
-
-func generateREST(in *descriptor.FileDescriptorProto) (*plugin_go.CodeGeneratorResponse_File, error) {
-	return generateBase(in, "%v.rest.pb.go", restTemplate)
-}
-
-func main() {
-	input, err := ioutil.ReadAll(os.Stdin)
+func generate(req *plugin_go.CodeGeneratorRequest) ([]*plugin_go.CodeGeneratorResponse_File, error) {
+	params, err := parseParameters(req.GetParameter())
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
-
-	req := plugin_go.CodeGeneratorRequest{}
-	err = proto.Unmarshal(input, &req)
+	outputs, err := params.outputs()
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
 
-	results := make([]*plugin_go.CodeGeneratorResponse_File, 0, len(req.ProtoFile))
-
+	files := make(map[string]*descriptor.FileDescriptorProto)
 	for _, file := range req.ProtoFile {
+		files[file.GetName()] = file
+	}
+	index, err := newTypeIndex(params, req.ProtoFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*plugin_go.CodeGeneratorResponse_File
+	for _, name := range req.FileToGenerate {
+		file, ok := files[name]
+		if !ok {
+			return nil, errors.New("missing descriptor of " + name)
+		}
 		if len(file.Service) == 0 {
 			continue
 		}
 
-		single, err := generate(file)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		results = append(results, single)
-
-		if strings.Contains(req.GetParameter(), "grpc") {
-			single, err := generateGRPC(file)
+		for _, out := range outputs {
+			single, err := generateBase(file, params, index, out)
 			if err != nil {
-				log.Fatalln(err)
+				return nil, err
 			}
-
 			results = append(results, single)
 		}
-		if strings.Contains(req.GetParameter(), "rest") {
-			single, err := generateREST(file)
-			if err != nil {
-				log.Fatalln(err)
-			}
+	}
 
-			results = append(results, single)
-		}
+	return results, nil
+}
 
+func main() {
+	input, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	res := &plugin_go.CodeGeneratorResponse{
-		File: results,
+	req := plugin_go.CodeGeneratorRequest{}
+	err = proto.Unmarshal(input, &req)
+	if err != nil {
+		log.Fatalln(err)
 	}
+
+	res := &plugin_go.CodeGeneratorResponse{}
+	results, err := generate(&req)
+	if err != nil {
+		// protoc reports the error with the name of the plugin
+		res.Error = stringPtr(err.Error())
+	} else {
+		res.File = results
+	}
+
 	result, err := proto.Marshal(res)
 	if err != nil {
 		log.Fatalln(err)
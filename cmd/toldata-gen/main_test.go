@@ -0,0 +1,339 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	plugin_go "github.com/gogo/protobuf/protoc-gen-gogo/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+const multiGoPackage = "github.com/citradigital/toldata/cmd/toldata-gen/testdata/multi;multi"
+
+// messagesSource stands for the code protoc-gen-gogo generates for the messages
+const messagesSource = `package multi
+
+type Request struct{ Input string }
+
+func (*Request) Reset()         {}
+func (*Request) String() string { return "" }
+func (*Request) ProtoMessage()  {}
+
+type Response struct{ Output string }
+
+func (*Response) Reset()         {}
+func (*Response) String() string { return "" }
+func (*Response) ProtoMessage()  {}
+
+type Response_Item struct{ Value string }
+
+func (*Response_Item) Reset()         {}
+func (*Response_Item) String() string { return "" }
+func (*Response_Item) ProtoMessage()  {}
+`
+
+func method(name, input, output string, clientStreaming, serverStreaming bool) *descriptor.MethodDescriptorProto {
+	return &descriptor.MethodDescriptorProto{
+		Name:            proto.String(name),
+		InputType:       proto.String(input),
+		OutputType:      proto.String(output),
+		ClientStreaming: proto.Bool(clientStreaming),
+		ServerStreaming: proto.Bool(serverStreaming),
+	}
+}
+
+// multiFileRequest has two files with services in the same Go package
+func multiFileRequest(parameter string) *plugin_go.CodeGeneratorRequest {
+	empty := &descriptor.FileDescriptorProto{
+		Name:        proto.String("google/protobuf/empty.proto"),
+		Package:     proto.String("google.protobuf"),
+		MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Empty")}},
+	}
+	a := &descriptor.FileDescriptorProto{
+		Name:    proto.String("multi/a.proto"),
+		Package: proto.String("multi"),
+		Options: &descriptor.FileOptions{GoPackage: proto.String(multiGoPackage)},
+		MessageType: []*descriptor.DescriptorProto{
+			{Name: proto.String("Request")},
+			{Name: proto.String("Response"), NestedType: []*descriptor.DescriptorProto{{Name: proto.String("Item")}}},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{
+			{Name: proto.String("Alpha"), Method: []*descriptor.MethodDescriptorProto{
+				method("Get", ".multi.Request", ".multi.Response", false, false),
+				method("List", ".multi.Request", ".multi.Response.Item", false, true),
+			}},
+			{Name: proto.String("Beta"), Method: []*descriptor.MethodDescriptorProto{
+				method("Feed", ".multi.Response.Item", ".multi.Response", true, false),
+			}},
+		},
+	}
+	b := &descriptor.FileDescriptorProto{
+		Name:       proto.String("multi/b.proto"),
+		Package:    proto.String("multi"),
+		Dependency: []string{"multi/a.proto", "google/protobuf/empty.proto"},
+		Options:    &descriptor.FileOptions{GoPackage: proto.String(multiGoPackage)},
+		Service: []*descriptor.ServiceDescriptorProto{
+			{Name: proto.String("Gamma"), Method: []*descriptor.MethodDescriptorProto{
+				method("Ping", ".google.protobuf.Empty", ".multi.Response", false, false),
+				method("Watch", ".multi.Request", ".google.protobuf.Empty", false, true),
+			}},
+		},
+	}
+
+	return &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{"multi/a.proto", "multi/b.proto"},
+		Parameter:      proto.String(parameter),
+		ProtoFile:      []*descriptor.FileDescriptorProto{empty, a, b},
+	}
+}
+
+func TestGenerateMultipleFiles(t *testing.T) {
+	defer os.Remove("testdata")
+
+	for _, parameter := range []string{"paths=source_relative,plugins=rest+mock", "paths=source_relative,client_only,plugins=mock", "paths=source_relative,server_only,plugins=mock"} {
+		t.Run(parameter, func(t *testing.T) {
+			files, err := generate(multiFileRequest(parameter))
+			assert.Equal(t, nil, err)
+
+			// The package is built inside the module so it can import the runtime
+			err = os.MkdirAll("testdata", 0755)
+			assert.Equal(t, nil, err)
+			dir, err := ioutil.TempDir("testdata", "multi")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			err = ioutil.WriteFile(filepath.Join(dir, "messages.go"), []byte(messagesSource), 0644)
+			assert.Equal(t, nil, err)
+			for _, file := range files {
+				err = ioutil.WriteFile(filepath.Join(dir, filepath.Base(file.GetName())), []byte(file.GetContent()), 0644)
+				assert.Equal(t, nil, err)
+			}
+
+			output, err := exec.Command("go", "vet", "./"+filepath.ToSlash(dir)).CombinedOutput()
+			assert.Equal(t, nil, err, string(output))
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := generate(multiFileRequest("plugins=unknown"))
+	assert.NotEqual(t, nil, err)
+
+	_, err = generate(multiFileRequest("client_only,server_only"))
+	assert.NotEqual(t, nil, err)
+
+	req := multiFileRequest("")
+	req.ProtoFile[1].Service[0].Method[0].InputType = proto.String(".other.Request")
+	_, err = generate(req)
+	assert.NotEqual(t, nil, err)
+
+	// A fire_and_forget method has no reply to return
+	req = multiFileRequest("")
+	m := req.ProtoFile[1].Service[0].Method[0]
+	m.Options = &descriptor.MethodOptions{}
+	err = proto.SetExtension(m.Options, fireAndForget, proto.Bool(true))
+	assert.Equal(t, nil, err)
+	_, err = generate(req)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestStreamingFlags(t *testing.T) {
+	// Get sets both flags to false, some compilers leave them unset instead
+	req := multiFileRequest("paths=source_relative,plugins=rest+mock")
+	unset := req.ProtoFile[1].Service[0].Method[0]
+	unset.Name, unset.ClientStreaming, unset.ServerStreaming = proto.String("Put"), nil, nil
+	req.ProtoFile[1].Service[0].Method = append(req.ProtoFile[1].Service[0].Method, method("Get", ".multi.Request", ".multi.Response", false, false))
+	files, err := generate(req)
+	assert.Equal(t, nil, err)
+
+	for _, file := range files {
+		if file.GetName() != "multi/a.toldata.pb.go" {
+			continue
+		}
+		for _, name := range []string{"Get", "Put"} {
+			assert.Contains(t, file.GetContent(), "\t"+name+"(ctx context.Context, req *Request) (*Response, error)\n")
+			assert.Contains(t, file.GetContent(), "func (service *AlphaToldataClient) "+name+"(ctx context.Context, req *Request) (*Response, error) {")
+			assert.NotContains(t, file.GetContent(), "Alpha_"+name+"Toldata")
+		}
+		assert.Contains(t, file.GetContent(), "List(req *Request, stream Alpha_ListToldataServer) error")
+		return
+	}
+	t.Fatal("multi/a.toldata.pb.go is not generated")
+}
+
+func TestMethodScopes(t *testing.T) {
+	req := multiFileRequest("paths=source_relative")
+	alpha := req.ProtoFile[1].Service[0]
+	alpha.Method = append(alpha.Method, method("Put", ".multi.Request", ".multi.Response", false, false))
+	alpha.Options = &descriptor.ServiceOptions{}
+	err := proto.SetExtension(alpha.Options, serviceScopes, []string{"orders", "audit", "billing"})
+	assert.Equal(t, nil, err)
+	for _, m := range alpha.Method {
+		m.Options = &descriptor.MethodOptions{}
+		err = proto.SetExtension(m.Options, scopes, []string{strings.ToLower(m.GetName())})
+		assert.Equal(t, nil, err)
+	}
+
+	// The options are decoded from the request as protoc sends it
+	data, err := proto.Marshal(req)
+	assert.Equal(t, nil, err)
+	req = &plugin_go.CodeGeneratorRequest{}
+	err = proto.Unmarshal(data, req)
+	assert.Equal(t, nil, err)
+
+	files, err := generate(req)
+	assert.Equal(t, nil, err)
+	for _, file := range files {
+		if file.GetName() != "multi/a.toldata.pb.go" {
+			continue
+		}
+		for _, name := range []string{"Get", "List", "Put"} {
+			requirement := fmt.Sprintf(`toldata.Requirement{Method: "multi.Alpha/%s", Scopes: []string{"orders", "audit", "billing", %q}}`, name, strings.ToLower(name))
+			assert.Contains(t, file.GetContent(), requirement)
+		}
+		return
+	}
+	t.Fatal("multi/a.toldata.pb.go is not generated")
+}
+
+// customTemplate uses the data model to list the methods in a file of its own name
+const customTemplate = `{{ define "filename" }}{{ .Base }}_methods.go{{ end }}package {{ .PackageName }}
+
+import (
+	{{ imports }}
+)
+
+{{ range .Services }}{{ range .Methods }}{{ if not .Streaming }}
+// {{ .Comments }}
+var _ = func(req *{{ .InputGoType }}) string { return "{{ .Subject }} {{ .FullName }} {{ .Timeout }}" }
+{{ end }}{{ end }}{{ end }}
+`
+
+func TestTemplatesDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "toldata-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "methods.tmpl"), []byte(customTemplate), 0644)
+	assert.Equal(t, nil, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "rest.tmpl"), []byte("package {{ .PackageName }}\n"), 0644)
+	assert.Equal(t, nil, err)
+
+	req := multiFileRequest("paths=source_relative,plugins=methods+rest,templates_dir=" + dir)
+	req.ProtoFile[1].SourceCodeInfo = &descriptor.SourceCodeInfo{Location: []*descriptor.SourceCodeInfo_Location{
+		{Path: []int32{6, 0, 2, 0}, LeadingComments: proto.String(" Get returns a response\n")},
+	}}
+	files, err := generate(req)
+	assert.Equal(t, nil, err)
+
+	contents := make(map[string]string)
+	for _, file := range files {
+		contents[file.GetName()] = file.GetContent()
+	}
+	assert.Equal(t, "package multi\n", contents["multi/a.rest.pb.go"])
+	methods := contents["multi/a_methods.go"]
+	assert.Contains(t, methods, "// Get returns a response")
+	assert.Contains(t, methods, `"multi/Alpha/Get multi.Alpha/Get 0s"`)
+	assert.Contains(t, methods, "func(req *Request)")
+	assert.Contains(t, contents["multi/b_methods.go"], "func(req *types.Empty)")
+	assert.Contains(t, contents, "multi/b.toldata.pb.go")
+
+	_, err = generate(multiFileRequest("plugins=methods"))
+	assert.NotEqual(t, nil, err)
+	_, err = generate(multiFileRequest("templates_dir=" + filepath.Join(dir, "missing")))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestParseParameters(t *testing.T) {
+	params, err := parseParameters("plugins=grpc+rest,paths=import,module=example.com/x,Ma.proto=example.com/x/a")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]bool{"grpc": true, "rest": true}, params.Plugins)
+	assert.Equal(t, "example.com/x/a", params.ImportMap["a.proto"])
+
+	file := &descriptor.FileDescriptorProto{Name: proto.String("a.proto")}
+	importPath, name, err := params.goPackage(file)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a", name)
+	output, err := params.outputName(file, importPath, "a.toldata.pb.go")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a/a.toldata.pb.go", output)
+
+	params, err = parseParameters("messages=golang,plugins=rest+mock")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "google.golang.org/protobuf/proto", params.protoPackage())
+	assert.Equal(t, toldatapbImportPath, params.runtimePackage())
+	assert.Equal(t, "google.golang.org/protobuf/types/known/fieldmaskpb", params.wellKnownPackage("google/protobuf/field_mask.proto"))
+	assert.Equal(t, "google.golang.org/protobuf/types/descriptorpb", params.wellKnownPackage("google/protobuf/descriptor.proto"))
+
+	for _, parameter := range []string{"unknown", "paths=relative", "server_only,plugins=rest", "client_only,plugins=grpcbackend", "module=x,paths=source_relative", "client_only=maybe", "messages=gogo2", "messages=golang,plugins=grpc", "messages=golang,server_only,plugins=grpcbackend"} {
+		_, err = parseParameters(parameter)
+		assert.NotEqual(t, nil, err, parameter)
+	}
+}
+
+func TestGoPackage(t *testing.T) {
+	params, err := parseParameters("Mb.proto=example.com/mapped/v1,Md.proto=example.com/mapped/d;delta")
+	assert.Equal(t, nil, err)
+	for _, c := range []struct{ file, goPackage, importPath, name string }{
+		{"a.proto", "example.com/x/a", "example.com/x/a", "a"},
+		{"a.proto", "example.com/x/a;alpha", "example.com/x/a", "alpha"},
+		{"a.proto", "example.com/x/go-a.v2", "example.com/x/go-a.v2", "go_a_v2"},
+		{"b.proto", "example.com/x/b;beta", "example.com/mapped/v1", "beta"},
+		{"b.proto", "", "example.com/mapped/v1", "v1"},
+		{"d.proto", "example.com/x/d;dee", "example.com/mapped/d", "delta"},
+	} {
+		file := &descriptor.FileDescriptorProto{Name: proto.String(c.file), Options: &descriptor.FileOptions{GoPackage: proto.String(c.goPackage)}}
+		importPath, name, err := params.goPackage(file)
+		assert.Equal(t, nil, err, c.goPackage)
+		assert.Equal(t, c.importPath, importPath, c.goPackage)
+		assert.Equal(t, c.name, name, c.goPackage)
+	}
+
+	_, _, err = params.goPackage(&descriptor.FileDescriptorProto{Name: proto.String("c.proto")})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestOutputName(t *testing.T) {
+	file := &descriptor.FileDescriptorProto{Name: proto.String("api/a.proto")}
+	for _, c := range []struct{ parameter, importPath, output string }{
+		{"", "example.com/x/a", "example.com/x/a/a.toldata.pb.go"},
+		{"module=example.com/x", "example.com/x/a", "a/a.toldata.pb.go"},
+		{"paths=source_relative", "example.com/x/a", "api/a.toldata.pb.go"},
+		{"module=example.com/x", "a", "api/a.toldata.pb.go"},
+	} {
+		params, err := parseParameters(c.parameter)
+		assert.Equal(t, nil, err)
+		output, err := params.outputName(file, c.importPath, "a.toldata.pb.go")
+		assert.Equal(t, nil, err, c.parameter)
+		assert.Equal(t, c.output, output, c.parameter)
+	}
+
+	params, err := parseParameters("module=example.com/y")
+	assert.Equal(t, nil, err)
+	_, err = params.outputName(file, "example.com/x/a", "a.toldata.pb.go")
+	assert.NotEqual(t, nil, err)
+}
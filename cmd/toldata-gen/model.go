@@ -0,0 +1,172 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+)
+
+// Field numbers of the descriptors, used to find the comments in SourceCodeInfo
+const (
+	fileServicePath    = 6
+	serviceMethodsPath = 2
+)
+
+// File is the data given to the templates, it is documented in the README
+type File struct {
+	// File is the name of the proto file, such as api/service.proto
+	File string
+	// Base is the name of the proto file without directory and extension
+	Base string
+	// PackageName and ImportPath are those of the generated Go package
+	PackageName string
+	ImportPath  string
+	// Namespace is the proto package
+	Namespace string
+	// Proto is the import path of the package marshalling the messages
+	Proto string
+	// Messages is gogo or golang
+	Messages string
+	// Client and Server tell whether the client and the server are generated
+	Client bool
+	Server bool
+	// Plugins are the outputs enabled with plugins=
+	Plugins  map[string]bool
+	Services []*Service
+}
+
+// Service is a service of the proto file, the fields of its descriptor are available
+type Service struct {
+	*descriptor.ServiceDescriptorProto
+	// FullName is the fully qualified proto name, such as pkg.Service
+	FullName string
+	// Subject is the base of the NATS subjects, <prefix>/<package>/<Service>
+	Subject    string
+	QueueGroup string
+	RestMount  string
+	// Version is the version option of the service
+	Version string
+	// Aliases are the subjects of the former names of the service
+	Aliases []string
+	// Comments are the leading comments of the service
+	Comments string
+	Methods  []*Method
+}
+
+// Method is a method of a service, the fields of its descriptor are available
+type Method struct {
+	*descriptor.MethodDescriptorProto
+	// FullName is the gRPC name of the method, such as pkg.Service/Method
+	FullName string
+	// Subject is the NATS subject of the method
+	Subject string
+	// Comments are the leading comments of the method
+	Comments      string
+	Timeout       time.Duration
+	Idempotent    bool
+	FireAndForget bool
+	Internal      bool
+	Event         bool
+	Durable       bool
+	// ClientStreaming and ServerStreaming hide the fields of the descriptor,
+	// which are set to false by some compilers
+	ClientStreaming bool
+	ServerStreaming bool
+	Streaming       bool
+	// Gather tells whether <Method>All is generated, for the unary calls waiting for a reply
+	Gather bool
+	// Scopes and Roles are the access required by the method and its service
+	Scopes []string
+	Roles  []string
+
+	im *imports
+}
+
+// InputGoType returns the Go type of the request, qualified and imported when needed
+func (m *Method) InputGoType() string {
+	return m.im.goType(m.GetInputType())
+}
+
+// OutputGoType returns the Go type of the response, qualified and imported when needed
+func (m *Method) OutputGoType() string {
+	return m.im.goType(m.GetOutputType())
+}
+
+func newFile(in *descriptor.FileDescriptorProto, params *parameters, importPath, packageName string, im *imports) *File {
+	namespace := in.GetPackage()
+	options := serviceOptions{namespace: namespace}
+	comments := make(map[string]string)
+	for _, location := range in.GetSourceCodeInfo().GetLocation() {
+		if location.LeadingComments != nil {
+			comments[fmt.Sprint(location.Path)] = strings.TrimSpace(location.GetLeadingComments())
+		}
+	}
+	comment := func(p ...int32) string {
+		return comments[fmt.Sprint(p)]
+	}
+
+	file := &File{
+		File:        in.GetName(),
+		Base:        strings.TrimSuffix(path.Base(in.GetName()), path.Ext(in.GetName())),
+		PackageName: packageName,
+		ImportPath:  importPath,
+		Namespace:   namespace,
+		Proto:       params.protoPackage(),
+		Messages:    params.Messages,
+		Client:      !params.ServerOnly,
+		Server:      !params.ClientOnly,
+		Plugins:     params.Plugins,
+	}
+	for i, s := range in.Service {
+		service := &Service{
+			ServiceDescriptorProto: s,
+			FullName:               namespace + "." + s.GetName(),
+			Subject:                options.subject(s),
+			QueueGroup:             options.queueGroup(s),
+			RestMount:              getRestMount(s),
+			Version:                getVersion(s),
+			Aliases:                options.aliases(s),
+			Comments:               comment(fileServicePath, int32(i)),
+		}
+		for j, m := range s.Method {
+			method := &Method{
+				MethodDescriptorProto: m,
+				FullName:              service.FullName + "/" + m.GetName(),
+				Subject:               service.Subject + "/" + m.GetName(),
+				Comments:              comment(fileServicePath, int32(i), serviceMethodsPath, int32(j)),
+				Timeout:               methodTimeout(m),
+				Idempotent:            isIdempotent(m),
+				FireAndForget:         isFireAndForget(m),
+				Internal:              isInternal(m),
+				Event:                 isEvent(m),
+				Durable:               isDurable(m),
+				ClientStreaming:       m.GetClientStreaming(),
+				ServerStreaming:       m.GetServerStreaming(),
+				Streaming:             m.GetClientStreaming() || m.GetServerStreaming(),
+				Scopes:                methodScopes(s, m),
+				Roles:                 methodRoles(s, m),
+				im:                    im,
+			}
+			method.Gather = !method.Streaming && !method.Event && !method.Durable && !method.FireAndForget
+			service.Methods = append(service.Methods, method)
+		}
+		file.Services = append(file.Services, service)
+	}
+	return file
+}
@@ -0,0 +1,234 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+)
+
+// The options of toldata.proto extend the descriptors of golang/protobuf,
+// the request is decoded into gogo's so the extensions are rebound to them
+var (
+	restMount      = extensionOf((*descriptor.ServiceOptions)(nil), toldata.E_RestMount)
+	subjectPrefix  = extensionOf((*descriptor.ServiceOptions)(nil), toldata.E_SubjectPrefix)
+	queueGroup     = extensionOf((*descriptor.ServiceOptions)(nil), toldata.E_QueueGroup)
+	version        = extensionOf((*descriptor.ServiceOptions)(nil), toldata.E_Version)
+	aliases        = extensionOf((*descriptor.ServiceOptions)(nil), toldata.E_Aliases)
+	serviceScopes  = extensionOf((*descriptor.ServiceOptions)(nil), toldata.E_ServiceScopes)
+	serviceRoles   = extensionOf((*descriptor.ServiceOptions)(nil), toldata.E_ServiceRoles)
+	defaultTimeout = extensionOf((*descriptor.MethodOptions)(nil), toldata.E_DefaultTimeout)
+	idempotent     = extensionOf((*descriptor.MethodOptions)(nil), toldata.E_Idempotent)
+	fireAndForget  = extensionOf((*descriptor.MethodOptions)(nil), toldata.E_FireAndForget)
+	internal       = extensionOf((*descriptor.MethodOptions)(nil), toldata.E_Internal)
+	event          = extensionOf((*descriptor.MethodOptions)(nil), toldata.E_Event)
+	durable        = extensionOf((*descriptor.MethodOptions)(nil), toldata.E_Durable)
+	scopes         = extensionOf((*descriptor.MethodOptions)(nil), toldata.E_Scopes)
+	roles          = extensionOf((*descriptor.MethodOptions)(nil), toldata.E_Roles)
+)
+
+func extensionOf(extended proto.Message, ext *proto.ExtensionDesc) *proto.ExtensionDesc {
+	desc := *ext
+	desc.ExtendedType = extended
+	return &desc
+}
+
+func stringOption(options proto.Message, ext *proto.ExtensionDesc) string {
+	value, err := proto.GetExtension(options, ext)
+	if err != nil {
+		return ""
+	}
+	return *value.(*string)
+}
+
+func stringsOption(options proto.Message, ext *proto.ExtensionDesc) []string {
+	value, err := proto.GetExtension(options, ext)
+	if err != nil {
+		return nil
+	}
+	return value.([]string)
+}
+
+func boolOption(options proto.Message, ext *proto.ExtensionDesc) bool {
+	value, err := proto.GetExtension(options, ext)
+	if err != nil {
+		return false
+	}
+	return *value.(*bool)
+}
+
+func serviceOption(service *descriptor.ServiceDescriptorProto, ext *proto.ExtensionDesc) string {
+	if service.Options == nil {
+		return ""
+	}
+	return stringOption(service.Options, ext)
+}
+
+func serviceList(service *descriptor.ServiceDescriptorProto, ext *proto.ExtensionDesc) []string {
+	if service.Options == nil {
+		return nil
+	}
+	return stringsOption(service.Options, ext)
+}
+
+func methodList(method *descriptor.MethodDescriptorProto, ext *proto.ExtensionDesc) []string {
+	if method.Options == nil {
+		return nil
+	}
+	return stringsOption(method.Options, ext)
+}
+
+func methodOption(method *descriptor.MethodDescriptorProto, ext *proto.ExtensionDesc) string {
+	if method.Options == nil {
+		return ""
+	}
+	return stringOption(method.Options, ext)
+}
+
+func methodFlag(method *descriptor.MethodDescriptorProto, ext *proto.ExtensionDesc) bool {
+	if method.Options == nil {
+		return false
+	}
+	return boolOption(method.Options, ext)
+}
+
+// serviceOptions resolves the subjects of a service for the templates
+type serviceOptions struct {
+	namespace string
+}
+
+// subject returns the base of the subjects of the service, <prefix>/<package>/<Service>
+func (o serviceOptions) subject(service *descriptor.ServiceDescriptorProto) string {
+	subject := o.namespace + "/" + service.GetName()
+	if prefix := serviceOption(service, subjectPrefix); prefix != "" {
+		subject = prefix + "/" + subject
+	}
+	return subject
+}
+
+func (o serviceOptions) queueGroup(service *descriptor.ServiceDescriptorProto) string {
+	if group := serviceOption(service, queueGroup); group != "" {
+		return group
+	}
+	return o.subject(service)
+}
+
+func getRestMount(service *descriptor.ServiceDescriptorProto) string {
+	if mount := serviceOption(service, restMount); mount != "" {
+		return mount
+	}
+	return "/api"
+}
+
+func getVersion(service *descriptor.ServiceDescriptorProto) string {
+	return serviceOption(service, version)
+}
+
+// aliases returns the subjects of the former names of the service
+func (o serviceOptions) aliases(service *descriptor.ServiceDescriptorProto) []string {
+	var subjects []string
+	for _, name := range serviceList(service, aliases) {
+		subjects = append(subjects, toldata.AliasSubject(serviceOption(service, subjectPrefix), name))
+	}
+	return subjects
+}
+
+// methodScopes returns the scopes required by the service and the method.
+// The service scopes are copied, the option keeps them in a slice shared by
+// all the methods.
+func methodScopes(service *descriptor.ServiceDescriptorProto, method *descriptor.MethodDescriptorProto) []string {
+	return append(append([]string(nil), serviceList(service, serviceScopes)...), methodList(method, scopes)...)
+}
+
+// methodRoles returns the roles allowed by the method, the ones of the service by default
+func methodRoles(service *descriptor.ServiceDescriptorProto, method *descriptor.MethodDescriptorProto) []string {
+	if roles := methodList(method, roles); len(roles) > 0 {
+		return roles
+	}
+	return serviceList(service, serviceRoles)
+}
+
+func methodTimeout(method *descriptor.MethodDescriptorProto) time.Duration {
+	timeout, _ := time.ParseDuration(methodOption(method, defaultTimeout))
+	return timeout
+}
+
+func isIdempotent(method *descriptor.MethodDescriptorProto) bool {
+	return methodFlag(method, idempotent)
+}
+
+func isFireAndForget(method *descriptor.MethodDescriptorProto) bool {
+	return methodFlag(method, fireAndForget)
+}
+
+func isInternal(method *descriptor.MethodDescriptorProto) bool {
+	return methodFlag(method, internal)
+}
+
+func isEvent(method *descriptor.MethodDescriptorProto) bool {
+	return methodFlag(method, event)
+}
+
+func isDurable(method *descriptor.MethodDescriptorProto) bool {
+	return methodFlag(method, durable)
+}
+
+func isEmpty(messageType string) bool {
+	return messageType == ".cdl.toldata.Empty" || messageType == ".google.protobuf.Empty"
+}
+
+// validateOptions reports the options which can not be generated
+func validateOptions(file *descriptor.FileDescriptorProto) error {
+	for _, service := range file.Service {
+		for _, method := range service.Method {
+			name := service.GetName() + "." + method.GetName()
+			if value := methodOption(method, defaultTimeout); value != "" {
+				timeout, err := time.ParseDuration(value)
+				if err != nil || timeout <= 0 {
+					return fmt.Errorf("%s: invalid default_timeout %q of %s", file.GetName(), value, name)
+				}
+			}
+			streaming := method.GetClientStreaming() || method.GetServerStreaming()
+			if isFireAndForget(method) {
+				if streaming {
+					return fmt.Errorf("%s: streaming method %s can not be fire_and_forget", file.GetName(), name)
+				}
+				if !isEmpty(method.GetOutputType()) {
+					return fmt.Errorf("%s: fire_and_forget method %s must return an Empty message", file.GetName(), name)
+				}
+			}
+			if isEvent(method) {
+				if streaming {
+					return fmt.Errorf("%s: streaming method %s can not be an event", file.GetName(), name)
+				}
+				if !isEmpty(method.GetOutputType()) {
+					return fmt.Errorf("%s: event %s must return an Empty message", file.GetName(), name)
+				}
+			}
+			if isDurable(method) {
+				if streaming || isEvent(method) || isFireAndForget(method) {
+					return fmt.Errorf("%s: durable method %s must be a unary call", file.GetName(), name)
+				}
+				if !isEmpty(method.GetOutputType()) {
+					return fmt.Errorf("%s: durable method %s must return an Empty message", file.GetName(), name)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// toldataOutput is always generated, the others are enabled with plugins=
+	toldataOutput  = "toldata"
+	templateSuffix = ".tmpl"
+)
+
+// output is a generated file
+type output struct {
+	name     string
+	suffix   string
+	template string
+}
+
+var builtinOutputs = []output{
+	{toldataOutput, ".toldata.pb.go", rpcTemplate},
+	{"grpc", ".grpc.pb.go", grpcTemplate},
+	{"grpcbackend", ".grpcbackend.pb.go", grpcBackendTemplate},
+	{"rest", ".rest.pb.go", restTemplate},
+	{"mock", ".mock.pb.go", mockTemplate},
+}
+
+// outputs returns the outputs enabled by the parameters. The <name>.tmpl files
+// of templates_dir replace the built-in template of the same name, the others
+// are extra outputs written to <file>.<name>.pb.go unless they define a
+// "filename" template.
+func (p *parameters) outputs() ([]output, error) {
+	all := append([]output(nil), builtinOutputs...)
+	if p.TemplatesDir != "" {
+		files, err := filepath.Glob(filepath.Join(p.TemplatesDir, "*"+templateSuffix))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no %s files in templates_dir %s", templateSuffix, p.TemplatesDir)
+		}
+
+		for _, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			name := strings.TrimSuffix(filepath.Base(file), templateSuffix)
+
+			found := false
+			for i := range all {
+				if all[i].name == name {
+					all[i].template = string(content)
+					found = true
+				}
+			}
+			if !found {
+				all = append(all, output{name: name, suffix: "." + name + ".pb.go", template: string(content)})
+			}
+		}
+	}
+
+	known := make(map[string]bool)
+	var result []output
+	for _, out := range all {
+		known[out.name] = true
+		if out.name == toldataOutput || p.Plugins[out.name] {
+			result = append(result, out)
+		}
+	}
+	for name := range p.Plugins {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown plugin %q", name)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+)
+
+const (
+	pathsImport         = "import"
+	pathsSourceRelative = "source_relative"
+
+	messagesGogo   = "gogo"
+	messagesGolang = "golang"
+)
+
+// plugins lists the optional built-in outputs, whether they need the generated client
+// and whether they only work with gogo messages
+var plugins = map[string]struct{ client, server, gogo bool }{
+	"grpc":        {client: true, gogo: true},
+	"rest":        {client: true},
+	"grpcbackend": {server: true, gogo: true},
+	"mock":        {},
+}
+
+// parameters are the options given with --toldata_out=<parameters>:<dir>
+type parameters struct {
+	Plugins map[string]bool
+	// Paths is either import or source_relative
+	Paths string
+	// ImportMap maps proto files to Go import paths, from M<file>=<path>
+	ImportMap map[string]string
+	// Module is stripped from the output paths when Paths is import
+	Module     string
+	ClientOnly bool
+	ServerOnly bool
+	// Messages is the generator of the messages, gogo or golang (APIv2)
+	Messages string
+	// TemplatesDir holds templates overriding or adding outputs
+	TemplatesDir string
+}
+
+func parseParameters(parameter string) (*parameters, error) {
+	p := &parameters{
+		Plugins:   make(map[string]bool),
+		Paths:     pathsImport,
+		ImportMap: make(map[string]string),
+		Messages:  messagesGogo,
+	}
+
+	for _, item := range strings.Split(parameter, ",") {
+		if item == "" {
+			continue
+		}
+
+		key, value := item, ""
+		hasValue := false
+		if i := strings.Index(item, "="); i != -1 {
+			key, value = item[:i], item[i+1:]
+			hasValue = true
+		}
+
+		var err error
+		switch {
+		case key == "plugins":
+			for _, name := range strings.Split(value, "+") {
+				if name == "" {
+					continue
+				}
+				p.Plugins[name] = true
+			}
+		case key == "paths":
+			if value != pathsImport && value != pathsSourceRelative {
+				return nil, fmt.Errorf("invalid paths %q, expected import or source_relative", value)
+			}
+			p.Paths = value
+		case key == "messages":
+			if value != messagesGogo && value != messagesGolang {
+				return nil, fmt.Errorf("invalid messages %q, expected gogo or golang", value)
+			}
+			p.Messages = value
+		case key == "templates_dir":
+			if value == "" {
+				return nil, errors.New("missing directory for templates_dir")
+			}
+			p.TemplatesDir = value
+		case key == "module":
+			p.Module = value
+		case key == "client_only":
+			p.ClientOnly, err = parseFlag(key, value, hasValue)
+		case key == "server_only":
+			p.ServerOnly, err = parseFlag(key, value, hasValue)
+		case strings.HasPrefix(key, "M") && len(key) > 1:
+			if !hasValue || value == "" {
+				return nil, fmt.Errorf("missing import path for %s", key[1:])
+			}
+			p.ImportMap[key[1:]] = value
+		default:
+			return nil, fmt.Errorf("unknown parameter %q", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.ClientOnly && p.ServerOnly {
+		return nil, errors.New("client_only and server_only are exclusive")
+	}
+	for name := range p.Plugins {
+		if p.ServerOnly && plugins[name].client {
+			return nil, fmt.Errorf("plugin %s needs the client, it can not be used with server_only", name)
+		}
+		if p.ClientOnly && plugins[name].server {
+			return nil, fmt.Errorf("plugin %s needs the server, it can not be used with client_only", name)
+		}
+		if p.Messages != messagesGogo && plugins[name].gogo {
+			return nil, fmt.Errorf("plugin %s needs gogo messages, it can not be used with messages=%s", name, p.Messages)
+		}
+	}
+	if p.Module != "" && p.Paths != pathsImport {
+		return nil, errors.New("module can only be used with paths=import")
+	}
+
+	return p, nil
+}
+
+// protoPackage returns the package marshalling the messages
+func (p *parameters) protoPackage() string {
+	if p.Messages == messagesGolang {
+		return "google.golang.org/protobuf/proto"
+	}
+	return "github.com/gogo/protobuf/proto"
+}
+
+// runtimePackage returns the package of the messages of toldata.proto
+func (p *parameters) runtimePackage() string {
+	if p.Messages == messagesGolang {
+		return toldatapbImportPath
+	}
+	return toldataImportPath
+}
+
+func parseFlag(key, value string, hasValue bool) (bool, error) {
+	if !hasValue {
+		return true, nil
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q for %s", value, key)
+	}
+	return result, nil
+}
+
+// goPackage returns the import path and the package name of the code
+// generated for a file, from its M mapping or its go_package option
+func (p *parameters) goPackage(file *descriptor.FileDescriptorProto) (string, string, error) {
+	importPath := file.GetOptions().GetGoPackage()
+	name := ""
+	if i := strings.Index(importPath, ";"); i != -1 {
+		importPath, name = importPath[:i], importPath[i+1:]
+	}
+	if mapped, ok := p.ImportMap[file.GetName()]; ok {
+		importPath = mapped
+		if i := strings.Index(mapped, ";"); i != -1 {
+			importPath, name = mapped[:i], mapped[i+1:]
+		}
+	}
+
+	if importPath == "" {
+		return "", "", errors.New("Unable to find go_package options in " + file.GetName())
+	}
+	if name == "" {
+		name = strings.NewReplacer("-", "_", ".", "_").Replace(path.Base(importPath))
+	}
+	return importPath, name, nil
+}
+
+// outputName returns the path of a generated file. With paths=import files go
+// to the directory of their import path, a go_package without any slash is
+// only a package name and keeps the files next to the proto file.
+func (p *parameters) outputName(file *descriptor.FileDescriptorProto, importPath, name string) (string, error) {
+	if p.Paths == pathsSourceRelative || !strings.Contains(importPath, "/") {
+		return path.Join(path.Dir(file.GetName()), name), nil
+	}
+
+	name = path.Join(importPath, name)
+	if p.Module != "" {
+		prefix := p.Module + "/"
+		if !strings.HasPrefix(name, prefix) {
+			return "", fmt.Errorf("%s: import path %s is not in module %s", file.GetName(), importPath, p.Module)
+		}
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return name, nil
+}
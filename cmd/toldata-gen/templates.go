@@ -7,37 +7,23 @@ const (
 package {{ .PackageName }}
 {{ $Namespace := .Namespace }}
 import (
-  "encoding/json"
+	"net/http"
+
 	"github.com/citradigital/toldata"
 	context "golang.org/x/net/context"
-	"net/http"
-	"time"
+	{{ imports }}
 )
 
-func throwError(w http.ResponseWriter, message string, code int) {
-	errorMessage := toldata.ErrorMessage{
-	  ErrorMessage: message,
-		Timestamp: time.Now().Unix(),
-	}
-  msg, err := json.Marshal(errorMessage)
-	if err != nil {
-		 http.Error(w, "{\"error-message\": \"internal-server-error\"}", http.StatusInternalServerError)
-	} else {
-	   http.Error(w, string(msg), code)
-	}
-} 
-
-{{ range .Services }}{{ $ServiceName := .Name }}
-{{ $Options := .Options }}
-
+{{ range .Services }}{{ $ServiceName := .Name }}{{ $RestMount := .RestMount }}
 
 type {{ $ServiceName }}REST struct {
 	Context context.Context
 	Bus     *toldata.Bus
-	Service *{{ $ServiceName }}ToldataClient
+	Service {{ $ServiceName }}ToldataClientInterface
+	Options toldata.RESTOptions
 }
 
-func New{{ $ServiceName }}REST(ctx context.Context, config toldata.ServiceConfiguration) (*{{ $ServiceName }}REST, error) {
+func New{{ $ServiceName }}REST(ctx context.Context, config toldata.ServiceConfiguration, options toldata.RESTOptions) (*{{ $ServiceName }}REST, error) {
 	client, err := toldata.NewBus(ctx, config)
 	if err != nil {
 		return nil, err
@@ -47,56 +33,74 @@ func New{{ $ServiceName }}REST(ctx context.Context, config toldata.ServiceConfig
 		Context: ctx,
 		Bus:     client,
 		Service: New{{ $ServiceName }}ToldataClient(client),
+		Options: options,
 	}
 
 	return &service, nil
 }
 
-func (svc *{{ $ServiceName }}REST) Install{{ $ServiceName }}Mux(mux *http.ServeMux) {
+// Routes describes the endpoints of the gateway so they can be mounted on any router
+func (svc *{{ $ServiceName }}REST) Routes() []toldata.RESTRoute {
+	return []toldata.RESTRoute{
+{{ range .Methods }}{{ if or .ClientStreaming .ServerStreaming .Internal }}{{ else }}		toldata.NewRESTRoute("{{ $Namespace }}.{{ $ServiceName }}", "{{ .Name }}", "{{ $RestMount }}/{{ $Namespace }}/{{ $ServiceName }}/{{ .Name }}", svc.Options, svc.serve{{ .Name }}),
+{{ end }}{{ end }}	}
+}
 
+// Handler returns a standalone http.Handler serving all routes of the gateway
+func (svc *{{ $ServiceName }}REST) Handler() http.Handler {
+	return toldata.RESTHandler(svc.Routes())
+}
 
-{{ range .Method }}	
+func (svc *{{ $ServiceName }}REST) Install{{ $ServiceName }}Mux(mux *http.ServeMux) {
+	for _, route := range svc.Routes() {
+		mux.Handle(route.Path, route.Handler)
+	}
+}
+
+{{ range .Methods }}	
 {{ $InputType := .InputType }}
 {{ $OutputType := .OutputType }}
-{{ if or .ClientStreaming .ServerStreaming }}
+{{ if or .ClientStreaming .ServerStreaming .Internal }}
 {{ else  }}
+func (svc *{{ $ServiceName }}REST) serve{{ .Name }}(w http.ResponseWriter, r *http.Request) {
+	contentType, err := svc.Options.Negotiate(r)
+	if err != nil {
+		svc.Options.WriteError(w, toldata.ContentTypeJSON, err.Error(), http.StatusNotAcceptable)
+		return
+	}
 
+	if r.Method != "POST" {
+		svc.Options.WriteError(w, contentType, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
 
+	var req {{ goType $InputType }}
+	code, err := svc.Options.ReadRequest(r, &req)
+	if err != nil {
+		svc.Options.WriteError(w, contentType, err.Error(), code)
+		return
+	}
+	peerInfo := svc.Options.Peer(r)
+	peerInfo.Gateway = svc.Bus.Configuration.ID
+	ctx := toldata.WithAuthorization(toldata.NewPeerContext(svc.Context, peerInfo), svc.Options.Authorization(r))
+{{ if .Event }}	err = svc.Service.Publish{{ .Name }}(ctx, &req)
+	if err != nil {
+		svc.Options.WriteCallError(w, contentType, err)
+		return
+	}
 
-  mux.HandleFunc("{{ getServiceOption $Options 99999 }}/{{ $Namespace }}/{{ $ServiceName }}/{{ .Name  }}", 
-	func (w http.ResponseWriter, r *http.Request) {
-		if r.Method != "POST" {
-			throwError(w, "Invalid request method", http.StatusMethodNotAllowed)
-			return
-		}
-
-		var req {{ stripLastDot $InputType $Namespace }}
-		err := json.NewDecoder(r.Body).Decode(&req)
-		if err != nil {
-			throwError(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		ip := strings.Split(r.RemoteAddr, ":")[0]
-		ipaddr := &net.IPAddr{IP: net.ParseIP(ip)}
-		peerInfo := &peer.Peer{Addr: ipaddr}
-		ctxWithPeer := peer.NewContext(svc.Context, peerInfo)
-		ret, err := svc.Service.{{ .Name }}(ctxWithPeer, &req)
-		if err != nil {
-			throwError(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	svc.Options.WriteResponse(w, contentType, &{{ goType $OutputType }}{})
+{{ else }}	ret, err := svc.Service.{{ .Name }}(ctx, &req)
+	if err != nil {
+		svc.Options.WriteCallError(w, contentType, err)
+		return
+	}
 
-		msg, err := json.Marshal(ret)
-		if err != nil {
-			throwError(w, err.Error(), http.StatusInternalServerError)
-			return
-		} else {
-			w.Write(msg)
-		}
-	})
-{{ end }}
+	svc.Options.WriteResponse(w, contentType, ret)
 {{ end }}
 }
+{{ end }}
+{{ end }}
 {{ end }}
 
 
@@ -109,24 +113,21 @@ package {{ .PackageName }}
 {{ $Namespace := .Namespace }}
 
 import (
-	"io"
 	"github.com/citradigital/toldata"
 	context "golang.org/x/net/context"
+	{{ imports }}
 )
 
-// Workaround for template problem
-func _eof_grpc() error {
-	return io.EOF
-}
 
-{{ range .Services }}{{ $ServiceName := .Name }}
+{{ range .Services }}{{ $ServiceName := .Name }}{{ $Subject := .Subject }}{{ $QueueGroup := .QueueGroup }}
 type {{ $ServiceName }}GRPC struct {
 	Context context.Context
 	Bus     *toldata.Bus
-	Service *{{ $ServiceName }}ToldataClient
+	Service {{ $ServiceName }}ToldataClientInterface
+	Options toldata.GRPCOptions
 }
 
-func New{{ $ServiceName }}GRPC(ctx context.Context, config toldata.ServiceConfiguration) (*{{ $ServiceName }}GRPC, error) {
+func New{{ $ServiceName }}GRPC(ctx context.Context, config toldata.ServiceConfiguration, options toldata.GRPCOptions) (*{{ $ServiceName }}GRPC, error) {
 	client, err := toldata.NewBus(ctx, config)
 	if err != nil {
 		return nil, err
@@ -136,6 +137,7 @@ func New{{ $ServiceName }}GRPC(ctx context.Context, config toldata.ServiceConfig
 		Context: ctx,
 		Bus:     client,
 		Service: New{{ $ServiceName }}ToldataClient(client),
+		Options: options,
 	}
 
 	return &service, nil
@@ -145,14 +147,32 @@ func (svc *{{ $ServiceName }}GRPC) Close() {
 	svc.Bus.Close()
 }
 
-{{ range .Method }}	
+func (svc *{{ $ServiceName }}GRPC) callContext(ctx context.Context) context.Context {
+	peerInfo := svc.Options.Peer(ctx)
+	peerInfo.Gateway = svc.Bus.Configuration.ID
+	return toldata.WithAuthorization(toldata.NewPeerContext(ctx, peerInfo), svc.Options.Authorization(ctx))
+}
+
+// ToldataServiceName returns the fully qualified name of the bridged service
+func (svc *{{ $ServiceName }}GRPC) ToldataServiceName() string {
+	return "{{ $Namespace }}.{{ $ServiceName }}"
+}
+
+// ToldataHealthCheck checks the health of the bridged service over the bus
+func (svc *{{ $ServiceName }}GRPC) ToldataHealthCheck(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error) {
+	return svc.Service.ToldataHealthCheck(ctx, req)
+}
+
+{{ range .Methods }}
 
 {{ $InputType := .InputType }}
 {{ $OutputType := .OutputType }}
 {{ if or .ClientStreaming .ServerStreaming }}
 {{ if .ClientStreaming }}
 func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(stream {{ $ServiceName }}_{{ .Name }}Server) error {
-	svrStream, err := svc.Service.{{ .Name }}(stream.Context())
+{{ if .Internal }}	return toldata.InternalMethodError("{{ $Namespace }}.{{ $ServiceName }}/{{ .Name }}")
+}
+{{ else }}	svrStream, err := svc.Service.{{ .Name }}(svc.callContext(stream.Context()))
 	if err != nil {
 		return err
 	}
@@ -161,7 +181,7 @@ func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(stream {{ $ServiceName }}_{{ .Nam
 		isEOF := false
 		data, err := stream.Recv()
 		if err != nil {
-			if err == io.EOF {
+			if err == {{ pkg "io" }}.EOF {
 				isEOF = true
 			} else {
 				return err
@@ -191,12 +211,14 @@ func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(stream {{ $ServiceName }}_{{ .Nam
 
 	return nil
 }
-{{ end }}
+{{ end }}{{ end }}
 
 {{ if .ServerStreaming }}
 
-func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(req *{{ stripLastDot $InputType $Namespace }}, stream {{ $ServiceName }}_{{ .Name }}Server) error {
-	svrStream, err := svc.Service.{{ .Name }}(stream.Context(), req)
+func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(req *{{ goType $InputType }}, stream {{ $ServiceName }}_{{ .Name }}Server) error {
+{{ if .Internal }}	return toldata.InternalMethodError("{{ $Namespace }}.{{ $ServiceName }}/{{ .Name }}")
+}
+{{ else }}	svrStream, err := svc.Service.{{ .Name }}(svc.callContext(stream.Context()), req)
 	if err != nil {
 		return err
 	}
@@ -204,6 +226,9 @@ func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(req *{{ stripLastDot $InputType $
 	for {
 		data, err := svrStream.Receive()
 
+		if err == {{ pkg "io" }}.EOF {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
@@ -214,15 +239,148 @@ func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(req *{{ stripLastDot $InputType $
 	}
 
 }
+{{ end }}{{ end }}
+{{ else }}
+func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error) {
+{{ if .Internal }}	return nil, toldata.InternalMethodError("{{ $Namespace }}.{{ $ServiceName }}/{{ .Name }}")
+{{ else if .Event }}	err := svc.Service.Publish{{ .Name }}(svc.callContext(ctx), req)
+	if err != nil {
+		return nil, err
+	}
+	return &{{ goType $OutputType }}{}, nil
+{{ else }}	return svc.Service.{{ .Name }}(svc.callContext(ctx), req)
+{{ end }}}
 {{ end }}
+{{ end }}
+{{ end }}
+
+`
+
+	grpcBackendTemplate = `// Code generated by github.com/citradigital/toldata. DO NOT EDIT.
+// package: {{ .Namespace }}
+// source: {{ .File }}
+package {{ .PackageName }}
+{{ $Namespace := .Namespace }}
+
+import (
+	"github.com/citradigital/toldata"
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+	{{ imports }}
+)
+
+
+{{ range .Services }}{{ $ServiceName := .Name }}{{ $Subject := .Subject }}{{ $QueueGroup := .QueueGroup }}
+// {{ $ServiceName }}GRPCBackend serves an existing gRPC service on the bus.
+// It implements {{ $ServiceName }}ToldataInterface by forwarding each call
+// to the gRPC connection.
+type {{ $ServiceName }}GRPCBackend struct {
+	Conn   *grpc.ClientConn
+	Client {{ $ServiceName }}Client
+}
+
+func New{{ $ServiceName }}GRPCBackend(conn *grpc.ClientConn) *{{ $ServiceName }}GRPCBackend {
+	return &{{ $ServiceName }}GRPCBackend{
+		Conn:   conn,
+		Client: New{{ $ServiceName }}Client(conn),
+	}
+}
+
+// ToldataHealthCheck reports the backend as unavailable while its connection is failing
+func (svc *{{ $ServiceName }}GRPCBackend) ToldataHealthCheck(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error) {
+	if svc.Conn != nil {
+		state := svc.Conn.GetState()
+		if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			return nil, status.Error(codes.Unavailable, "grpc backend is "+state.String())
+		}
+	}
+	return &{{ runtime "ToldataHealthCheckInfo" }}{}, nil
+}
+
+{{ range .Methods }}
+{{ $InputType := .InputType }}
+{{ $OutputType := .OutputType }}
+{{ if .ClientStreaming }}
+func (svc *{{ $ServiceName }}GRPCBackend) {{ .Name }}(stream {{ $ServiceName }}_{{ .Name }}ToldataServer) {
+	ctx := context.Background()
+	if impl, ok := stream.(*{{ $ServiceName }}_{{ .Name }}ToldataServerImpl); ok {
+		ctx = impl.Context
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client, err := svc.Client.{{ .Name }}(ctx)
+	if err != nil {
+		stream.Error(err)
+		return
+	}
+
+	for {
+		data, err := stream.Receive()
+		if err == {{ pkg "io" }}.EOF {
+			break
+		}
+		if err != nil {
+			stream.Error(err)
+			return
+		}
+
+		err = client.Send(data)
+		if err != nil {
+			// The actual error is reported by CloseAndRecv
+			break
+		}
+	}
+
+	resp, err := client.CloseAndRecv()
+	if err != nil {
+		stream.Error(err)
+		return
+	}
+
+	err = stream.Done(resp)
+	if err != nil {
+		stream.Error(err)
+	}
+}
+{{ else if .ServerStreaming }}
+func (svc *{{ $ServiceName }}GRPCBackend) {{ .Name }}(req *{{ goType $InputType }}, stream {{ $ServiceName }}_{{ .Name }}ToldataServer) error {
+	ctx := context.Background()
+	if impl, ok := stream.(*{{ $ServiceName }}_{{ .Name }}ToldataServerImpl); ok {
+		ctx = impl.Context
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client, err := svc.Client.{{ .Name }}(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := client.Recv()
+		if err == {{ pkg "io" }}.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = stream.Send(resp)
+		if err != nil {
+			return err
+		}
+	}
+}
 {{ else }}
-func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(ctx context.Context, req *{{ stripLastDot $InputType $Namespace }}) (*{{ stripLastDot $OutputType $Namespace }}, error) {
-	return svc.Service.{{ .Name }}(ctx, req)
+func (svc *{{ $ServiceName }}GRPCBackend) {{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error) {
+	return svc.Client.{{ .Name }}(ctx, req)
 }
 {{ end }}
 {{ end }}
 {{ end }}
-
 `
 
 	rpcTemplate = `// Code generated by github.com/citradigital/toldata. DO NOT EDIT.
@@ -232,105 +390,245 @@ func (svc *{{ $ServiceName }}GRPC) {{ .Name }}(ctx context.Context, req *{{ stri
 package {{ .PackageName }}
 import (
 	"context"
-	"errors"
-   io "io"
-	"github.com/gogo/protobuf/proto"
+	"{{ .Proto }}"
 	"github.com/citradigital/toldata"
-	nats "github.com/nats-io/nats.go"
+{{ if .Server }}	nats "github.com/nats-io/nats.go"{{ end }}
+	{{ imports }}
 )
 
-// Workaround for template problem
-func _eof() error {
-	return io.EOF
-}
 
 {{ $Namespace := .Namespace }}
-{{ range .Services }}{{ $ServiceName := .Name }}
+{{ if .Server }}{{ range .Services }}{{ $ServiceName := .Name }}{{ $Subject := .Subject }}{{ $QueueGroup := .QueueGroup }}
 
 type {{ .Name }}ToldataInterface interface {
-	ToldataHealthCheck(ctx context.Context, req *toldata.Empty) (*toldata.ToldataHealthCheckInfo, error)
+	ToldataHealthCheck(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error)
 
-	{{ range .Method }}
+	{{ range .Methods }}
 
 {{ $InputType := .InputType }}
 {{ $OutputType := .OutputType }}
 	{{ if or .ClientStreaming .ServerStreaming }}
 		{{ if .ServerStreaming }}
-		{{ .Name }}(req *{{ stripLastDot $InputType $Namespace }}, stream {{ $ServiceName }}_{{ .Name }}ToldataServer) error
+		{{ .Name }}(req *{{ goType $InputType }}, stream {{ $ServiceName }}_{{ .Name }}ToldataServer) error
 		{{ else }}
 			{{ .Name }}(stream {{ $ServiceName }}_{{ .Name }}ToldataServer)
 		{{ end }}
 	{{ else }}
-		{{ .Name }}(ctx context.Context, req *{{ stripLastDot $InputType $Namespace }}) (*{{ stripLastDot $OutputType $Namespace }}, error){{ end }}
+		{{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error){{ end }}
 	{{ end }}
-}{{ end }}
-{{ range .Services }}{{ $ServiceName := .Name }}
-type {{ $ServiceName }}ToldataClient struct {
-	Bus *toldata.Bus
 }
 
-type {{ $ServiceName }}ToldataServer struct {
+// Unimplemented{{ $ServiceName }}ToldataServer is embedded in implementations of {{ $ServiceName }}ToldataInterface
+// so they keep compiling when methods are added. Its methods fail with toldata.UnimplementedMethodError,
+// its ToldataHealthCheck reports the service as healthy.
+type Unimplemented{{ $ServiceName }}ToldataServer struct{}
+
+var _ {{ $ServiceName }}ToldataInterface = Unimplemented{{ $ServiceName }}ToldataServer{}
+
+func (Unimplemented{{ $ServiceName }}ToldataServer) ToldataHealthCheck(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error) {
+	return &{{ runtime "ToldataHealthCheckInfo" }}{}, nil
+}
+{{ range .Methods }}{{ $InputType := .InputType }}{{ $OutputType := .OutputType }}{{ if .ServerStreaming }}
+func (Unimplemented{{ $ServiceName }}ToldataServer) {{ .Name }}(req *{{ goType $InputType }}, stream {{ $ServiceName }}_{{ .Name }}ToldataServer) error {
+	return toldata.UnimplementedMethodError("{{ $Namespace }}.{{ $ServiceName }}/{{ .Name }}")
+}
+{{ else if .ClientStreaming }}
+func (Unimplemented{{ $ServiceName }}ToldataServer) {{ .Name }}(stream {{ $ServiceName }}_{{ .Name }}ToldataServer) {
+	stream.Error(toldata.UnimplementedMethodError("{{ $Namespace }}.{{ $ServiceName }}/{{ .Name }}"))
+}
+{{ else }}
+func (Unimplemented{{ $ServiceName }}ToldataServer) {{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error) {
+	return nil, toldata.UnimplementedMethodError("{{ $Namespace }}.{{ $ServiceName }}/{{ .Name }}")
+}
+{{ end }}{{ end }}{{ end }}{{ end }}
+{{ range .Services }}{{ $ServiceName := .Name }}{{ $Subject := .Subject }}{{ $QueueGroup := .QueueGroup }}
+{{ if $.Client }}
+// {{ $ServiceName }}ToldataClientInterface is implemented by {{ $ServiceName }}ToldataClient
+type {{ $ServiceName }}ToldataClientInterface interface {
+	ToldataHealthCheck(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error)
+	ToldataHealthCheckAll(ctx context.Context, req *{{ runtime "Empty" }}, options toldata.GatherOptions) ([]*{{ $ServiceName }}_ToldataHealthCheckReply, error)
+{{ range .Methods }}{{ $InputType := .InputType }}{{ $OutputType := .OutputType }}{{ if .ServerStreaming }}
+	{{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) ({{ $ServiceName }}_{{ .Name }}ToldataClient, error){{ else if .ClientStreaming }}
+	{{ .Name }}(ctx context.Context) ({{ $ServiceName }}_{{ .Name }}ToldataClient, error){{ else if .Event }}
+	Publish{{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) error{{ else }}
+	{{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error){{ end }}{{ if .Gather }}
+	{{ .Name }}All(ctx context.Context, req *{{ goType $InputType }}, options toldata.GatherOptions) ([]*{{ $ServiceName }}_{{ .Name }}Reply, error){{ end }}{{ end }}
+}
+
+type {{ $ServiceName }}ToldataClient struct {
 	Bus *toldata.Bus
-	Service {{ $ServiceName }}ToldataInterface
+	// Version selects the versions of the servers called, any version by default
+	Version toldata.VersionPolicy
+	// Subject is the subject of the service called, such as the one of an
+	// alias, {{ $Subject }} by default
+	Subject string
 }
 
+var _ {{ $ServiceName }}ToldataClientInterface = (*{{ $ServiceName }}ToldataClient)(nil)
+
 func New{{ $ServiceName }}ToldataClient(bus *toldata.Bus) * {{$ServiceName}}ToldataClient {
 	s := &{{ $ServiceName }}ToldataClient{ Bus: bus }
 	return s
 }
 
+func (service *{{ $ServiceName }}ToldataClient) serviceSubject() string {
+	if service.Subject != "" {
+		return service.Subject
+	}
+	return "{{ $Subject }}"
+}
+
+// {{ $ServiceName }}_ToldataHealthCheckReply is the reply of a server to ToldataHealthCheckAll
+type {{ $ServiceName }}_ToldataHealthCheckReply struct {
+	BusID    string
+	Response *{{ runtime "ToldataHealthCheckInfo" }}
+	Err      error
+}
+
+// ToldataHealthCheckAll checks the health of every server
+func (service *{{ $ServiceName }}ToldataClient) ToldataHealthCheckAll(ctx context.Context, req *{{ runtime "Empty" }}, options toldata.GatherOptions) ([]*{{ $ServiceName }}_ToldataHealthCheckReply, error) {
+	functionName := service.Bus.Subject(service.serviceSubject(), "ToldataHealthCheck")
+	ctx, subject := service.Version.Route(ctx, functionName)
+
+	reqRaw, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := service.Bus.Gather(ctx, subject, reqRaw, options)
+	result := make([]*{{ $ServiceName }}_ToldataHealthCheckReply, 0, len(replies))
+	for _, reply := range replies {
+		r := &{{ $ServiceName }}_ToldataHealthCheckReply{BusID: reply.BusID, Err: reply.Err}
+		if r.Err == nil {
+			r.Response = &{{ runtime "ToldataHealthCheckInfo" }}{}
+			r.Err = proto.Unmarshal(reply.Data, r.Response)
+		}
+		result = append(result, r)
+	}
+	return result, err
+}
+{{ range .Methods }}{{ if .Gather }}
+// {{ $ServiceName }}_{{ .Name }}Reply is the reply of a server to {{ .Name }}All
+type {{ $ServiceName }}_{{ .Name }}Reply struct {
+	BusID    string
+	Response *{{ .OutputGoType }}
+	Err      error
+}
+
+// {{ .Name }}All calls {{ .Name }} on every server and returns their replies
+func (service *{{ $ServiceName }}ToldataClient) {{ .Name }}All(ctx context.Context, req *{{ .InputGoType }}, options toldata.GatherOptions) ([]*{{ $ServiceName }}_{{ .Name }}Reply, error) {
+	functionName := service.Bus.Subject(service.serviceSubject(), "{{ .Name }}")
+	ctx, subject := service.Version.Route(ctx, functionName)
+
+	if req == nil {
+		return nil, toldata.ErrEmptyRequest
+	}
+	reqRaw, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := service.Bus.Gather(ctx, subject, reqRaw, options)
+	result := make([]*{{ $ServiceName }}_{{ .Name }}Reply, 0, len(replies))
+	for _, reply := range replies {
+		r := &{{ $ServiceName }}_{{ .Name }}Reply{BusID: reply.BusID, Err: reply.Err}
+		if r.Err == nil {
+			r.Response = &{{ .OutputGoType }}{}
+			r.Err = proto.Unmarshal(reply.Data, r.Response)
+		}
+		result = append(result, r)
+	}
+	return result, err
+}
+{{ end }}{{ end }}
+{{ end }}
+{{ if $.Server }}
+type {{ $ServiceName }}ToldataServer struct {
+	Bus *toldata.Bus
+	Service {{ $ServiceName }}ToldataInterface
+	// EventDelivery tells whether the events are handled by one server of the
+	// queue group or by all of them
+	EventDelivery toldata.EventDelivery
+	// Durable configures the consumers of the durable methods
+	Durable toldata.DurableOptions
+	// Discovery registers the server with the NATS micro protocol when set
+	Discovery *toldata.DiscoveryOptions
+	// Aliases are former subjects of the service, such as pkg/OldService, on
+	// which the server also receives the calls, after the aliases option
+	Aliases []string
+	// OnAlias is told of the calls received on the subjects of an alias
+	OnAlias toldata.AliasObserver
+
+	registration *toldata.Registration
+}
+
 func New{{ $ServiceName }}ToldataServer(bus *toldata.Bus, service {{ $ServiceName }}ToldataInterface) * {{$ServiceName}}ToldataServer {
 	s := &{{ $ServiceName }}ToldataServer{ Bus: bus, Service: service }
 	return s
 }
 
-func (service *{{ $ServiceName }}ToldataClient) ToldataHealthCheck(ctx context.Context, req *toldata.Empty) (*toldata.ToldataHealthCheckInfo, error) {
-	functionName := "{{ $Namespace }}/{{ $ServiceName }}/ToldataHealthCheck"
+// serviceSubjects returns the subject of the service followed by the ones of its aliases
+func (service *{{ $ServiceName }}ToldataServer) serviceSubjects() []string {
+	return append([]string{"{{ $Subject }}"{{ range .Aliases }}, "{{ . }}"{{ end }}}, service.Aliases...)
+}
+
+// aliasHandler tells the alias observers of the calls of a method received on
+// the subjects of base, when it is an alias
+func (service *{{ $ServiceName }}ToldataServer) aliasHandler(base, method string, handler nats.MsgHandler) nats.MsgHandler {
+	if base == "{{ $Subject }}" {
+		return handler
+	}
+	return toldata.WithAlias(base, method, handler, service.registration.ObserveAlias, service.OnAlias)
+}
+{{ end }}
+{{ if $.Client }}
+func (service *{{ $ServiceName }}ToldataClient) ToldataHealthCheck(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error) {
+	functionName := service.Bus.Subject(service.serviceSubject(), "ToldataHealthCheck")
+	ctx, subject := service.Version.Route(ctx, functionName)
 	
 	reqRaw, err := proto.Marshal(req)
 
-	result, err := service.Bus.Connection.RequestWithContext(ctx, functionName, reqRaw)
+	result, err := service.Bus.Request(ctx, subject, reqRaw)
 	if err != nil {
-		return nil, errors.New(functionName + ":" + err.Error())
+		return nil, toldata.RequestError(functionName, err)
 	}
 
 	if result.Data[0] == 0 {
 		// 0 means no error
-		p := &toldata.ToldataHealthCheckInfo{}
+		p := &{{ runtime "ToldataHealthCheckInfo" }}{}
 		err = proto.Unmarshal(result.Data[1:], p)
 		if err != nil {
 			return nil, err
 		}
 		return p, nil
 	} else {
-		var pErr toldata.ErrorMessage
+		var pErr {{ runtime "ErrorMessage" }}
 		err = proto.Unmarshal(result.Data[1:], &pErr)
 		if err == nil {
-			return nil, errors.New(pErr.ErrorMessage)
+			return nil, pErr.Err()
 		} else {
 			return nil, err
 		}
 	}
 }
+{{ end }}
 
 
-
-{{ range .Method }}	
+{{ range .Methods }}	
 
 {{ $InputType := .InputType }}
 {{ $OutputType := .OutputType }}
 {{ if or .ClientStreaming .ServerStreaming }}
+{{ if $.Server }}
 type {{ $ServiceName }}_{{ .Name }}ToldataServer interface {
 	{{ if .ClientStreaming }}
-	Receive() (*{{ stripLastDot $InputType $Namespace }}, error)
-	OnData(*{{ stripLastDot $InputType $Namespace }}) error
-	Done(resp *{{ stripLastDot $OutputType $Namespace }}) error
+	Receive() (*{{ goType $InputType }}, error)
+	OnData(*{{ goType $InputType }}) error
+	Done(resp *{{ goType $OutputType }}) error
 	{{ end }}
 
-	GetResponse() (*{{ stripLastDot $OutputType $Namespace }}, error)
+	GetResponse() (*{{ goType $OutputType }}, error)
 
 	{{ if .ServerStreaming }}
-	Send(*{{ stripLastDot $OutputType $Namespace }}) error
+	Send(*{{ goType $OutputType }}) error
 	{{ end }}
 	
 	TriggerEOF()
@@ -346,10 +644,10 @@ type {{ $ServiceName }}_{{ .Name }}ToldataServerImpl struct {
 	{{ if .ServerStreaming }}
 	{{ end }}
 
-	request   chan *{{ stripLastDot $InputType $Namespace }}
+	request   chan *{{ goType $InputType }}
 	isRequestClosed bool
 
-	response chan *{{ stripLastDot $OutputType $Namespace }}
+	response chan *{{ goType $OutputType }}
 	
 	cancel chan struct{}
 	eof    chan struct{}
@@ -373,8 +671,8 @@ func Create{{ $ServiceName }}_{{ .Name }}ToldataServerImpl(ctx context.Context)
 	{{ end }}
 	
 	t.Context = ctx
-	t.request = make(chan *{{ stripLastDot $InputType $Namespace }})
-	t.response = make(chan *{{ stripLastDot $OutputType $Namespace }}, 1024)
+	t.request = make(chan *{{ goType $InputType }})
+	t.response = make(chan *{{ goType $OutputType }}, 1024)
 	t.cancel = make(chan struct{})
 	t.eof = make(chan struct{})
 	t.done = make(chan struct{})
@@ -407,13 +705,13 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) TriggerEOF() {
 
 {{ if .ClientStreaming }}
 
-func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Receive() (*{{ stripLastDot $InputType $Namespace }}, error) {
+func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Receive() (*{{ goType $InputType }}, error) {
 
 	if impl.streamErr != nil {
 		return nil, impl.streamErr
 	}
 	if impl.isEOF {
-		return nil, io.EOF
+		return nil, {{ pkg "io" }}.EOF
 	}
 
 	select {
@@ -422,7 +720,7 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Receive() (*{{ stri
 	case <-impl.cancel:
 		return nil, impl.streamErr
 	case <-impl.eof:
-		return nil, io.EOF
+		return nil, {{ pkg "io" }}.EOF
 	case err := <-impl.err:
 
 		return nil, err
@@ -430,7 +728,7 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Receive() (*{{ stri
 	}
 }
 
-func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) OnData(req *{{ stripLastDot $InputType $Namespace }}) error {
+func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) OnData(req *{{ goType $InputType }}) error {
 	if impl.streamErr != nil {
 		return impl.streamErr
 	}
@@ -443,7 +741,7 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) OnData(req *{{ stri
 	}
 }
 
-func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Done(resp *{{ stripLastDot $OutputType $Namespace }}) error {
+func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Done(resp *{{ goType $OutputType }}) error {
 	if impl.streamErr != nil {
 		return impl.streamErr
 	}
@@ -460,7 +758,7 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Done(resp *{{ strip
 
 {{ end }}
 
-func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) GetResponse() (*{{ stripLastDot $OutputType $Namespace }}, error) {
+func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) GetResponse() (*{{ goType $OutputType }}, error) {
 	if impl.streamErr != nil {
 		return nil, impl.streamErr
 	}
@@ -477,25 +775,31 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) GetResponse() (*{{
 		{{ if .ServerStreaming }}
 		impl.Exit()
 		{{ end }}
-		return nil, errors.New("canceled")
+		return nil, toldata.ErrStreamCanceled
 
 	case response := <-impl.response:
 		return response, nil
 
 		{{ if .ServerStreaming }}
 	case <-impl.eof:
+		// Responses sent before the end of the stream are delivered first
+		select {
+		case response := <-impl.response:
+			return response, nil
+		default:
+		}
 		impl.Exit()
-		return nil, io.EOF
+		return nil, {{ pkg "io" }}.EOF
 		{{ end }}
 	}
 }
 
 
 {{ if .ServerStreaming }}
-func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Send(req *{{ stripLastDot $OutputType $Namespace }}) error {
+func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Send(req *{{ goType $OutputType }}) error {
 
 	if impl.isEOF {
-		return io.EOF
+		return {{ pkg "io" }}.EOF
 	}
 
 	if impl.streamErr != nil {
@@ -508,7 +812,7 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Send(req *{{ stripL
 	case <-impl.cancel:
 		return impl.streamErr
 	case <-impl.eof:
-		return io.EOF
+		return {{ pkg "io" }}.EOF
 	case err := <-impl.err:
 		return err
 
@@ -531,6 +835,14 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Error(err error) {
 	impl.err <- err
 	impl.streamErr = err
 }
+{{ end }}
+{{ if $.Client }}
+// {{ $ServiceName }}_{{ .Name }}ToldataClient is the client side of a {{ .Name }} stream
+type {{ $ServiceName }}_{{ .Name }}ToldataClient interface {
+{{ if .ClientStreaming }}	Send(req *{{ goType $InputType }}) error
+{{ end }}{{ if .ServerStreaming }}	Receive() (*{{ goType $OutputType }}, error)
+{{ end }}	Done() (*{{ goType $OutputType }}, error)
+}
 
 type {{ $ServiceName }}ToldataClient_{{ .Name }} struct {
 	Context context.Context
@@ -540,25 +852,25 @@ type {{ $ServiceName }}ToldataClient_{{ .Name }} struct {
 
 {{ if .ClientStreaming }}
 
-func (client *{{ $ServiceName }}ToldataClient_{{ .Name }}) Send(req *{{ stripLastDot $InputType $Namespace }}) error {
-	functionName := "{{ $Namespace }}/{{ $ServiceName }}/{{ .Name }}_Send_" + client.ID
+func (client *{{ $ServiceName }}ToldataClient_{{ .Name }}) Send(req *{{ goType $InputType }}) error {
+	functionName := client.Service.Bus.Subject(client.Service.serviceSubject(), "{{ .Name }}") + "_Send_" + client.ID
 	if req == nil {
-		return errors.New("empty-request")
+		return toldata.ErrEmptyRequest
 	}
 	reqRaw, err := proto.Marshal(req)
-	result, err := client.Service.Bus.Connection.RequestWithContext(client.Context, functionName, reqRaw)
+	result, err := client.Service.Bus.Request(client.Context, functionName, reqRaw)
 	if err != nil {
-		return errors.New(functionName + ":" + err.Error())
+		return toldata.RequestError(functionName, err)
 	}
 
 	if result.Data[0] == 0 {
 		// 0 means no error
 		return nil
 	} else {
-		var pErr toldata.ErrorMessage
+		var pErr {{ runtime "ErrorMessage" }}
 		err = proto.Unmarshal(result.Data[1:], &pErr)
 		if err == nil {
-			return errors.New(pErr.ErrorMessage)
+			return pErr.Err()
 		} else {
 			return err
 		}
@@ -568,27 +880,27 @@ func (client *{{ $ServiceName }}ToldataClient_{{ .Name }}) Send(req *{{ stripLas
 {{ end }}
 {{ if .ServerStreaming }}
 
-func (client *{{ $ServiceName }}ToldataClient_{{ .Name }}) Receive() (*{{ stripLastDot $OutputType $Namespace }}, error) {
-	functionName := "{{ $Namespace }}/{{ $ServiceName }}/{{ .Name }}_Receive_" + client.ID
+func (client *{{ $ServiceName }}ToldataClient_{{ .Name }}) Receive() (*{{ goType $OutputType }}, error) {
+	functionName := client.Service.Bus.Subject(client.Service.serviceSubject(), "{{ .Name }}") + "_Receive_" + client.ID
 	
-	result, err := client.Service.Bus.Connection.RequestWithContext(client.Context, functionName, nil)
+	result, err := client.Service.Bus.Request(client.Context, functionName, nil)
 	if err != nil {
-		return nil, errors.New(functionName + ":" + err.Error())
+		return nil, toldata.RequestError(functionName, err)
 	}
 
 	if result.Data[0] == 0 {
 		// 0 means no error
-		p := &{{ stripLastDot $OutputType $Namespace }}{}
+		p := &{{ goType $OutputType }}{}
 		err = proto.Unmarshal(result.Data[1:], p)
 		if err != nil {
 			return nil, err
 		}
 		return p, nil
 	} else {
-		var pErr toldata.ErrorMessage
+		var pErr {{ runtime "ErrorMessage" }}
 		err = proto.Unmarshal(result.Data[1:], &pErr)
 		if err == nil {
-			return nil, errors.New(pErr.ErrorMessage)
+			return nil, pErr.Err()
 		} else {
 			return nil, err
 		}
@@ -597,43 +909,53 @@ func (client *{{ $ServiceName }}ToldataClient_{{ .Name }}) Receive() (*{{ stripL
 {{ end }}
 
 
-func (client *{{ $ServiceName }}ToldataClient_{{ .Name }}) Done() (*{{ stripLastDot $OutputType $Namespace }}, error) {
-	functionName := "{{ $Namespace }}/{{ $ServiceName }}/{{ .Name }}_Done_" + client.ID
+func (client *{{ $ServiceName }}ToldataClient_{{ .Name }}) Done() (*{{ goType $OutputType }}, error) {
+	functionName := client.Service.Bus.Subject(client.Service.serviceSubject(), "{{ .Name }}") + "_Done_" + client.ID
 
-	result, err := client.Service.Bus.Connection.RequestWithContext(client.Context, functionName, nil)
+	result, err := client.Service.Bus.Request(client.Context, functionName, nil)
 
 	if err != nil {
-		return nil, errors.New(functionName + ":" + err.Error())
+		return nil, toldata.RequestError(functionName, err)
 	}
 
 	if result.Data[0] == 0 {
 		// 0 means no error
-		p := &{{ stripLastDot $OutputType $Namespace }}{}
+		p := &{{ goType $OutputType }}{}
 		err = proto.Unmarshal(result.Data[1:], p)
 		if err != nil {
 			return nil, err
 		}
 		return p, nil
 	} else {
-		var pErr toldata.ErrorMessage
+		var pErr {{ runtime "ErrorMessage" }}
 		err = proto.Unmarshal(result.Data[1:], &pErr)
 		if err == nil {
-			return nil, errors.New(pErr.ErrorMessage)
+			return nil, pErr.Err()
 		} else {
 			return nil, err
 		}
 	}
 }
 
+{{ end }}
+{{ if $.Server }}
 func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Subscribe(service *{{ $ServiceName }}ToldataServer, id string) error {
 	bus := service.Bus
 	var sub *nats.Subscription
 	var subscriptions []*nats.Subscription
 	var err error
 
+	// fail undoes the subscriptions made before one of them failed
+	fail := func(err error) error {
+		for i := range subscriptions {
+			subscriptions[i].Unsubscribe()
+		}
+		return err
+	}
+
 	{{ if .ClientStreaming }}
-	sub, err = bus.Connection.QueueSubscribe("{{ $Namespace}}/{{ $ServiceName }}/{{ .Name }}_Send_"+id, "{{ $Namespace}}/{{ $ServiceName }}", func(m *nats.Msg) {
-		var input {{ stripLastDot $InputType $Namespace }}
+	handleSend := func(m *nats.Msg) {
+		var input {{ goType $InputType }}
 		err := proto.Unmarshal(m.Data, &input)
 		if err != nil {
 			bus.HandleError(m.Reply, err)
@@ -650,11 +972,9 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Subscribe(service *
 			bus.Connection.Publish(m.Reply, zero)
 		}
 
-	})
-
-	subscriptions = append(subscriptions, sub)
+	}
 
-	sub, err = bus.Connection.QueueSubscribe("{{ $Namespace}}/{{ $ServiceName }}/{{ .Name }}_Done_"+id, "{{ $Namespace}}/{{ $ServiceName }}", func(m *nats.Msg) {
+	handleDone := func(m *nats.Msg) {
 
 		defer impl.Exit()
 		impl.TriggerEOF()
@@ -672,15 +992,28 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Subscribe(service *
 			bus.Connection.Publish(m.Reply, append(zero, raw...))
 		}
 
-	})
+	}
 
-	subscriptions = append(subscriptions, sub)
+	for _, base := range service.serviceSubjects() {
+		for _, server := range bus.ServerSubjects(base, "{{ .Name }}", "{{ $QueueGroup }}") {
+			sub, err = bus.Connection.QueueSubscribe(server.Subject+"_Send_"+id, server.QueueGroup, handleSend)
+			if err != nil {
+				return fail(err)
+			}
+			subscriptions = append(subscriptions, sub)
+			sub, err = bus.Connection.QueueSubscribe(server.Subject+"_Done_"+id, server.QueueGroup, handleDone)
+			if err != nil {
+				return fail(err)
+			}
+			subscriptions = append(subscriptions, sub)
+		}
+	}
 
 	{{ end }}
 
 	{{ if .ServerStreaming }}
-	sub, err = bus.Connection.QueueSubscribe("{{ $Namespace}}/{{ $ServiceName }}/{{ .Name }}_Receive_"+id, "{{ $Namespace}}/{{ $ServiceName }}", func(m *nats.Msg) {
-		var input {{ stripLastDot $InputType $Namespace }}
+	handleReceive := func(m *nats.Msg) {
+		var input {{ goType $InputType }}
 		err := proto.Unmarshal(m.Data, &input)
 		if err != nil {
 			bus.HandleError(m.Reply, err)
@@ -701,9 +1034,17 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Subscribe(service *
 			bus.Connection.Publish(m.Reply, append(zero, raw...))
 		}
 
-	})
+	}
 
-	subscriptions = append(subscriptions, sub)
+	for _, base := range service.serviceSubjects() {
+		for _, server := range bus.ServerSubjects(base, "{{ .Name }}", "{{ $QueueGroup }}") {
+			sub, err = bus.Connection.QueueSubscribe(server.Subject+"_Receive_"+id, server.QueueGroup, handleReceive)
+			if err != nil {
+				return fail(err)
+			}
+			subscriptions = append(subscriptions, sub)
+		}
+	}
 	{{ end }}
 
 
@@ -713,35 +1054,36 @@ func (impl *{{ $ServiceName }}_{{ .Name }}ToldataServerImpl) Subscribe(service *
 			}
 	})
 
-	return err
+	return nil
 }
+{{ end }}
 
-
-
-
+{{ if $.Client }}
 {{ if .ServerStreaming }}
-func (service *{{ $ServiceName }}ToldataClient) {{ .Name }}(ctx context.Context, req *{{ stripLastDot $InputType $Namespace }}) (*{{ $ServiceName }}ToldataClient_{{ .Name }}, error) {
-	functionName := "{{ $Namespace }}/{{ $ServiceName }}/{{ .Name }}"
+func (service *{{ $ServiceName }}ToldataClient) {{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) ({{ $ServiceName }}_{{ .Name }}ToldataClient, error) {
+	functionName := service.Bus.Subject(service.serviceSubject(), "{{ .Name }}")
+	ctx, subject := service.Version.Route(ctx, functionName)
 	if req == nil {
-		return nil, errors.New("empty-request")
+		return nil, toldata.ErrEmptyRequest
 	}
 	reqRaw, err := proto.Marshal(req)	
-	result, err := service.Bus.Connection.RequestWithContext(ctx, functionName, reqRaw)
+	result, err := service.Bus.Request(ctx, subject, reqRaw)
 {{ else }}
-func (service *{{ $ServiceName }}ToldataClient) {{ .Name }}(ctx context.Context) (*{{ $ServiceName }}ToldataClient_{{ .Name }}, error) {
-	functionName := "{{ $Namespace }}/{{ $ServiceName }}/{{ .Name }}"
+func (service *{{ $ServiceName }}ToldataClient) {{ .Name }}(ctx context.Context) ({{ $ServiceName }}_{{ .Name }}ToldataClient, error) {
+	functionName := service.Bus.Subject(service.serviceSubject(), "{{ .Name }}")
+	ctx, subject := service.Version.Route(ctx, functionName)
 	
-	result, err := service.Bus.Connection.RequestWithContext(ctx, functionName, nil)
+	result, err := service.Bus.Request(ctx, subject, nil)
 
 {{ end }}
 	if err != nil {
-		return nil, errors.New(functionName + ":" + err.Error())
+		return nil, toldata.RequestError(functionName, err)
 	}
 
 	if result.Data[0] == 0 {
 		// 0 means no error
 
-		p := &toldata.StreamInfo{}
+		p := &{{ runtime "StreamInfo" }}{}
 		err = proto.Unmarshal(result.Data[1:], p)
 		if err != nil {
 			return nil, err
@@ -752,56 +1094,96 @@ func (service *{{ $ServiceName }}ToldataClient) {{ .Name }}(ctx context.Context)
 			Service: service,
 		}, nil
 	} else {
-		var pErr toldata.ErrorMessage
+		var pErr {{ runtime "ErrorMessage" }}
 		err = proto.Unmarshal(result.Data[1:], &pErr)
 		if err == nil {
-			return nil, errors.New(pErr.ErrorMessage)
+			return nil, pErr.Err()
 		} else {
 			return nil, err
 		}
 	}
 }
+{{ end }}
 
-{{ else }}
+{{ else if and $.Client .Event }}
+
+// Publish{{ .Name }} publishes the event to the servers subscribed to it
+func (service *{{ $ServiceName }}ToldataClient) Publish{{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) error {
+	functionName := service.Bus.Subject(service.serviceSubject(), "{{ .Name }}")
+	ctx, subject := service.Version.Route(ctx, functionName)
 
-func (service *{{ $ServiceName }}ToldataClient) {{ .Name }}(ctx context.Context, req *{{ stripLastDot $InputType $Namespace }}) (*{{ stripLastDot $OutputType $Namespace }}, error) {
-	functionName := "{{ $Namespace }}/{{ $ServiceName }}/{{ .Name }}"
-	
 	if req == nil {
-		return nil, errors.New("empty-request")
+		return toldata.ErrEmptyRequest
 	}
 	reqRaw, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	err = service.Bus.Publish(ctx, subject, reqRaw)
+	if err != nil {
+		return toldata.RequestError(functionName, err)
+	}
+	return nil
+}
 
-	result, err := service.Bus.Connection.RequestWithContext(ctx, functionName, reqRaw)
+{{ else if $.Client }}
+
+func (service *{{ $ServiceName }}ToldataClient) {{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error) {
+	functionName := service.Bus.Subject(service.serviceSubject(), "{{ .Name }}"){{ if not .Durable }}
+	ctx, subject := service.Version.Route(ctx, functionName){{ end }}
+	
+	if req == nil {
+		return nil, toldata.ErrEmptyRequest
+	}
+	reqRaw, err := proto.Marshal(req)
+{{ if .FireAndForget }}
+	err = service.Bus.Publish(ctx, subject, reqRaw)
 	if err != nil {
-		return nil, errors.New(functionName + ":" + err.Error())
+		return nil, toldata.RequestError(functionName, err)
+	}
+	return &{{ goType $OutputType }}{}, nil
+}
+{{ else }}{{ if .Timeout }}
+	ctx, cancel := toldata.WithDefaultTimeout(ctx, {{ printf "%d" .Timeout }}) // {{ .Timeout }}
+	defer cancel()
+{{ end }}{{ if .Durable }}
+	err = service.Bus.PublishDurable(ctx, functionName, reqRaw)
+	if err != nil {
+		return nil, toldata.RequestError(functionName, err)
+	}
+	return &{{ goType $OutputType }}{}, nil
+}
+{{ else }}
+	result, err := service.Bus.{{ if .Idempotent }}RequestIdempotent{{ else }}Request{{ end }}(ctx, subject, reqRaw)
+	if err != nil {
+		return nil, toldata.RequestError(functionName, err)
 	}
 
 	if result.Data[0] == 0 {
 		// 0 means no error
-		p := &{{ stripLastDot $OutputType $Namespace }}{}
+		p := &{{ goType $OutputType }}{}
 		err = proto.Unmarshal(result.Data[1:], p)
 		if err != nil {
 			return nil, err
 		}
 		return p, nil
 	} else {
-		var pErr toldata.ErrorMessage
+		var pErr {{ runtime "ErrorMessage" }}
 		err = proto.Unmarshal(result.Data[1:], &pErr)
 		if err == nil {
-			return nil, errors.New(pErr.ErrorMessage)
+			return nil, pErr.Err()
 		} else {
 			return nil, err
 		}
 	}
 }
-
+{{ end }}{{ end }}
 {{ end }}
 
 {{ end }}
 {{ end }}
 
-{{ range .Services }}{{ $ServiceName := .Name }}
+{{ if .Server }}{{ range .Services }}{{ $ServiceName := .Name }}{{ $Subject := .Subject }}{{ $QueueGroup := .QueueGroup }}
 
 
 func (service *{{ $ServiceName }}ToldataServer) Subscribe{{ .Name }}() (<-chan struct{}, error) {
@@ -810,22 +1192,63 @@ func (service *{{ $ServiceName }}ToldataServer) Subscribe{{ .Name }}() (<-chan s
 	var err error
 	var sub *nats.Subscription
 	var subscriptions []*nats.Subscription
+
+	// The servers of a version also receive the calls on the versioned subjects
+	version := bus.ServiceVersion("{{ .Version }}")
+
+	if service.Discovery != nil {
+		discovery := *service.Discovery
+		if discovery.Version == "" {
+			discovery.Version = version
+		}
+		service.registration, err = bus.Register(discovery, "{{ .FullName }}",{{ range .Methods }}
+			toldata.DiscoveryEndpoint{Name: "{{ .Name }}", Subject: bus.Subject("{{ $Subject }}", "{{ .Name }}"), QueueGroup: bus.QueueGroup("{{ $QueueGroup }}")},{{ end }}
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
 	
+	// fail undoes the subscriptions and the registration made before one of
+	// the subscriptions failed
+	fail := func(err error) (<-chan struct{}, error) {
+		for i := range subscriptions {
+			subscriptions[i].Unsubscribe()
+		}
+		service.registration.Close()
+		return nil, err
+	}
+
 	done := make(chan struct{})
 	
-	{{ range .Method }}	
+	{{ range .Methods }}	
 
 {{ $InputType := .InputType }}
 {{ $OutputType := .OutputType }}
 	{{ if or .ClientStreaming .ServerStreaming }}
-	sub, err = bus.Connection.QueueSubscribe("{{ $Namespace }}/{{ $ServiceName }}/{{ .Name }}", "{{ $Namespace}}/{{ $ServiceName }}", func(m *nats.Msg) {
-		stream := Create{{ $ServiceName }}_{{ .Name }}ToldataServerImpl(bus.Context)
+	handle{{ .Name }} := func(m *nats.Msg) {
+		track := service.registration.Track("{{ .Name }}", m)
+		ctx, err := bus.Authenticate(m)
+		if err == nil {
+			err = bus.Authorize(ctx, toldata.Requirement{Method: "{{ .FullName }}"{{ if .Scopes }}, Scopes: {{ printf "%#v" .Scopes }}{{ end }}{{ if .Roles }}, Roles: {{ printf "%#v" .Roles }}{{ end }}})
+		}
+		if err != nil {
+			track(err)
+			bus.HandleError(m.Reply, err)
+			return
+		}
+		stream := Create{{ $ServiceName }}_{{ .Name }}ToldataServerImpl(ctx)
 
 		
 
-		stream.Subscribe(service, m.Reply)
+		err = stream.Subscribe(service, m.Reply)
+		if err != nil {
+			track(err)
+			bus.HandleError(m.Reply, err)
+			return
+		}
 
-		raw, err := proto.Marshal(&toldata.StreamInfo{
+		raw, err := proto.Marshal(&{{ runtime "StreamInfo" }}{
 			ID: m.Reply,
 		})
 		if err != nil {
@@ -835,13 +1258,15 @@ func (service *{{ $ServiceName }}ToldataServer) Subscribe{{ .Name }}() (<-chan s
 			bus.Connection.Publish(m.Reply, append(zero, raw...))
 		}
 		{{ if .ServerStreaming }}
-		var input {{ stripLastDot $InputType $Namespace }}
+		var input {{ goType $InputType }}
 		err = proto.Unmarshal(m.Data, &input)
 		if err != nil {
+			track(err)
 			bus.HandleError(m.Reply, err)
 			return
 		}
 		err = service.Service.{{ .Name }}(&input, stream)
+		track(err)
 		if err != nil {
 			stream.Error(err)
 			bus.HandleError(m.Reply, err)
@@ -853,20 +1278,78 @@ func (service *{{ $ServiceName }}ToldataServer) Subscribe{{ .Name }}() (<-chan s
 		stream.TriggerEOF()
 		{{ else }}
 		service.Service.{{ .Name }}(stream)
+		track(nil)
 		{{ end }}
-	})
+	}
+	for _, base := range service.serviceSubjects() {
+		handler := service.aliasHandler(base, "{{ .Name }}", handle{{ .Name }})
+		for _, server := range bus.ServerSubjects(base, "{{ .Name }}", "{{ $QueueGroup }}") {
+			for _, subject := range append([]string{server.Subject}, toldata.VersionSubjects(server.Subject, version)...) {
+				sub, err = bus.Connection.QueueSubscribe(subject, server.QueueGroup, handler)
+				if err != nil {
+					return fail(err)
+				}
+				subscriptions = append(subscriptions, sub)
+			}
+		}
+	}
 
-	subscriptions = append(subscriptions, sub)
+	{{ else if .Durable }}
+	handle{{ .Name }} := func(m *nats.Msg) error {
+		track := service.registration.Track("{{ .Name }}", m)
+		ctx, err := bus.Authenticate(m)
+		if err == nil {
+			err = bus.Authorize(ctx, toldata.Requirement{Method: "{{ .FullName }}"{{ if .Scopes }}, Scopes: {{ printf "%#v" .Scopes }}{{ end }}{{ if .Roles }}, Roles: {{ printf "%#v" .Roles }}{{ end }}})
+		}
+		if err != nil {
+			track(err)
+			return err
+		}
+		var input {{ goType $InputType }}
+		err = proto.Unmarshal(m.Data, &input)
+		if err != nil {
+			track(err)
+			return err
+		}
+		_, err = service.Service.{{ .Name }}(ctx, &input)
+		track(err)
+		return err
+	}
+	for _, base := range service.serviceSubjects() {
+		var handler toldata.DurableHandler = handle{{ .Name }}
+		if base != "{{ $Subject }}" {
+			handler = toldata.WithDurableAlias(base, "{{ .Name }}", handler, service.registration.ObserveAlias, service.OnAlias)
+		}
+		for _, server := range bus.ServerSubjects(base, "{{ .Name }}", "{{ $QueueGroup }}") {
+			sub, err = bus.SubscribeDurable(server, "{{ .Name }}", service.Durable, handler)
+			if err != nil {
+				return fail(err)
+			}
+			subscriptions = append(subscriptions, sub)
+		}
+	}
 
 	{{ else }}
-	sub, err = bus.Connection.QueueSubscribe("{{ $Namespace }}/{{ $ServiceName }}/{{ .Name }}", "{{ $Namespace}}/{{ $ServiceName }}", func(m *nats.Msg) {
-		var input {{ stripLastDot $InputType $Namespace }}
-		err := proto.Unmarshal(m.Data, &input)
+	handle{{ .Name }} := func(m *nats.Msg) {
+		track := service.registration.Track("{{ .Name }}", m)
+		ctx, err := bus.Authenticate(m)
+		if err == nil {
+			err = bus.Authorize(ctx, toldata.Requirement{Method: "{{ .FullName }}"{{ if .Scopes }}, Scopes: {{ printf "%#v" .Scopes }}{{ end }}{{ if .Roles }}, Roles: {{ printf "%#v" .Roles }}{{ end }}})
+		}
 		if err != nil {
+			track(err)
 			bus.HandleError(m.Reply, err)
 			return
 		}
-		result, err := service.Service.{{ .Name }}(bus.Context, &input)
+		var input {{ goType $InputType }}
+		err = proto.Unmarshal(m.Data, &input)
+		if err != nil {
+			track(err)
+			bus.HandleError(m.Reply, err)
+			return
+		}
+		result, err := service.Service.{{ .Name }}(ctx, &input)
+		track(err)
 
 		if m.Reply != ""  {
 			if err != nil {
@@ -877,14 +1360,31 @@ func (service *{{ $ServiceName }}ToldataServer) Subscribe{{ .Name }}() (<-chan s
 					bus.HandleError(m.Reply, err)
 				} else {
 					zero := []byte{0}
-					bus.Connection.Publish(m.Reply, append(zero, raw...))
+					bus.Reply(m.Reply, append(zero, raw...))
 				}
 			}
 		}
 
-	})
+	}
+	for _, base := range service.serviceSubjects() {
+		handler := service.aliasHandler(base, "{{ .Name }}", handle{{ .Name }})
+		for _, server := range bus.ServerSubjects(base, "{{ .Name }}", "{{ $QueueGroup }}") {
+			for _, subject := range append([]string{server.Subject}, toldata.VersionSubjects(server.Subject, version)...) {
+				sub, err = {{ if .Event }}bus.SubscribeEvents(subject, server.QueueGroup, service.EventDelivery, handler){{ else }}bus.Connection.QueueSubscribe(subject, server.QueueGroup, handler){{ end }}
+				if err != nil {
+					return fail(err)
+				}
+				subscriptions = append(subscriptions, sub){{ if not .Event }}
+				// Every server receives the gather calls
+				sub, err = bus.Connection.Subscribe(toldata.GatherSubject(subject), handler)
+				if err != nil {
+					return fail(err)
+				}
+				subscriptions = append(subscriptions, sub){{ end }}
+			}
+		}
+	}
 
-	subscriptions = append(subscriptions, sub)
 	{{ end }}
 
 
@@ -892,14 +1392,14 @@ func (service *{{ $ServiceName }}ToldataServer) Subscribe{{ .Name }}() (<-chan s
 	{{ end }}
 
 
-	sub, err = bus.Connection.QueueSubscribe("{{ $Namespace }}/{{ $ServiceName }}/ToldataHealthCheck", "{{ $Namespace}}/{{ $ServiceName }}", func(m *nats.Msg) {
-		var input toldata.Empty
+	handleToldataHealthCheck := func(m *nats.Msg) {
+		var input {{ runtime "Empty" }}
 		err := proto.Unmarshal(m.Data, &input)
 		if err != nil {
 			bus.HandleError(m.Reply, err)
 			return
 		}
-		result, err := service.Service.ToldataHealthCheck(bus.Context, &input)
+		result, err := service.Service.ToldataHealthCheck(bus.CallContext(m), &input)
 
 		if m.Reply != ""  {
 			if err != nil {
@@ -910,14 +1410,29 @@ func (service *{{ $ServiceName }}ToldataServer) Subscribe{{ .Name }}() (<-chan s
 					bus.HandleError(m.Reply, err)
 				} else {
 					zero := []byte{0}
-					bus.Connection.Publish(m.Reply, append(zero, raw...))
+					bus.Reply(m.Reply, append(zero, raw...))
 				}
 			}
 		}
 
-	})
-
-	subscriptions = append(subscriptions, sub)
+	}
+	for _, base := range service.serviceSubjects() {
+		handler := service.aliasHandler(base, "ToldataHealthCheck", handleToldataHealthCheck)
+		for _, server := range bus.ServerSubjects(base, "ToldataHealthCheck", "{{ $QueueGroup }}") {
+			for _, subject := range append([]string{server.Subject}, toldata.VersionSubjects(server.Subject, version)...) {
+				sub, err = bus.Connection.QueueSubscribe(subject, server.QueueGroup, handler)
+				if err != nil {
+					return fail(err)
+				}
+				subscriptions = append(subscriptions, sub)
+				sub, err = bus.Connection.Subscribe(toldata.GatherSubject(subject), handler)
+				if err != nil {
+					return fail(err)
+				}
+				subscriptions = append(subscriptions, sub)
+			}
+		}
+	}
 
 
 	go func() {
@@ -928,16 +1443,300 @@ func (service *{{ $ServiceName }}ToldataServer) Subscribe{{ .Name }}() (<-chan s
 			for i := range subscriptions {
 				subscriptions[i].Unsubscribe()
 			}
+			service.registration.Close()
 		}
 	}()
 
-	return done, err
+	return done, nil
 }
 
 
 
+{{ end }}{{ end }}
+
+
+`
+
+	mockTemplate = `// Code generated by github.com/citradigital/toldata. DO NOT EDIT.
+// package: {{ .Namespace }}
+// source: {{ .File }}
+
+package {{ .PackageName }}
+
+import (
+	"context"
+	"github.com/citradigital/toldata"
+	{{ imports }}
+)
+
+{{ range .Services }}{{ $ServiceName := .Name }}
+{{ range .Methods }}{{ $InputType := .InputType }}{{ $OutputType := .OutputType }}
+// mock{{ $ServiceName }}{{ .Name }}Result is a result scripted for {{ .Name }}
+type mock{{ $ServiceName }}{{ .Name }}Result struct {
+{{ if .ServerStreaming }}	responses []*{{ goType $OutputType }}
+{{ else }}	response *{{ goType $OutputType }}
+{{ end }}	err error
+}
+{{ end }}
+
+{{ if $.Client }}
+// {{ $ServiceName }}ToldataClientMock is a fake {{ $ServiceName }}ToldataClientInterface. It records the
+// calls and returns the results scripted with the Return methods, or those of the Func fields when set.
+type {{ $ServiceName }}ToldataClientMock struct {
+	toldata.Mock
+
+	ToldataHealthCheckFunc func(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error)
+	ToldataHealthCheckAllFunc func(ctx context.Context, req *{{ runtime "Empty" }}, options toldata.GatherOptions) ([]*{{ $ServiceName }}_ToldataHealthCheckReply, error)
+{{ range .Methods }}{{ $InputType := .InputType }}{{ $OutputType := .OutputType }}{{ if .ServerStreaming }}
+	{{ .Name }}Func func(ctx context.Context, req *{{ goType $InputType }}) ({{ $ServiceName }}_{{ .Name }}ToldataClient, error){{ else if .ClientStreaming }}
+	{{ .Name }}Func func(ctx context.Context) ({{ $ServiceName }}_{{ .Name }}ToldataClient, error){{ else if .Event }}
+	Publish{{ .Name }}Func func(ctx context.Context, req *{{ goType $InputType }}) error{{ else }}
+	{{ .Name }}Func func(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error){{ end }}{{ if .Gather }}
+	{{ .Name }}AllFunc func(ctx context.Context, req *{{ goType $InputType }}, options toldata.GatherOptions) ([]*{{ $ServiceName }}_{{ .Name }}Reply, error){{ end }}{{ end }}
+}
+
+var _ {{ $ServiceName }}ToldataClientInterface = (*{{ $ServiceName }}ToldataClientMock)(nil)
+
+func (m *{{ $ServiceName }}ToldataClientMock) ToldataHealthCheck(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error) {
+	m.Record("ToldataHealthCheck", req)
+	if m.ToldataHealthCheckFunc != nil {
+		return m.ToldataHealthCheckFunc(ctx, req)
+	}
+	return &{{ runtime "ToldataHealthCheckInfo" }}{}, nil
+}
+
+// ToldataHealthCheckAll returns the result of ToldataHealthCheck as the reply of a single server
+func (m *{{ $ServiceName }}ToldataClientMock) ToldataHealthCheckAll(ctx context.Context, req *{{ runtime "Empty" }}, options toldata.GatherOptions) ([]*{{ $ServiceName }}_ToldataHealthCheckReply, error) {
+	if m.ToldataHealthCheckAllFunc != nil {
+		m.Record("ToldataHealthCheckAll", req)
+		return m.ToldataHealthCheckAllFunc(ctx, req, options)
+	}
+	resp, err := m.ToldataHealthCheck(ctx, req)
+	return []*{{ $ServiceName }}_ToldataHealthCheckReply{ {BusID: toldata.MockBusID, Response: resp, Err: err} }, nil
+}
+{{ range .Methods }}{{ $InputType := .InputType }}{{ $OutputType := .OutputType }}{{ if .Gather }}
+// {{ .Name }}All returns the result of {{ .Name }} as the reply of a single server
+func (m *{{ $ServiceName }}ToldataClientMock) {{ .Name }}All(ctx context.Context, req *{{ goType $InputType }}, options toldata.GatherOptions) ([]*{{ $ServiceName }}_{{ .Name }}Reply, error) {
+	if m.{{ .Name }}AllFunc != nil {
+		m.Record("{{ .Name }}All", req)
+		return m.{{ .Name }}AllFunc(ctx, req, options)
+	}
+	resp, err := m.{{ .Name }}(ctx, req)
+	return []*{{ $ServiceName }}_{{ .Name }}Reply{ {BusID: toldata.MockBusID, Response: resp, Err: err} }, nil
+}
+{{ end }}
+{{ if .ServerStreaming }}
+// Return{{ .Name }} scripts the responses of a {{ .Name }} stream, it ends with err or io.EOF when err is nil
+func (m *{{ $ServiceName }}ToldataClientMock) Return{{ .Name }}(responses []*{{ goType $OutputType }}, err error) {
+	m.Script("{{ .Name }}", &mock{{ $ServiceName }}{{ .Name }}Result{responses: responses, err: err})
+}
+
+func (m *{{ $ServiceName }}ToldataClientMock) {{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) ({{ $ServiceName }}_{{ .Name }}ToldataClient, error) {
+	call := m.Record("{{ .Name }}", req)
+	if m.{{ .Name }}Func != nil {
+		return m.{{ .Name }}Func(ctx, req)
+	}
+	result, ok := m.Next("{{ .Name }}")
+	if !ok {
+		return nil, toldata.ErrNotScripted
+	}
+	r := result.(*mock{{ $ServiceName }}{{ .Name }}Result)
+	return &{{ $ServiceName }}_{{ .Name }}ToldataClientMock{mock: &m.Mock, call: call, responses: r.responses, err: r.err}, nil
+}
+{{ else if .ClientStreaming }}
+// Return{{ .Name }} scripts the response of a {{ .Name }} stream
+func (m *{{ $ServiceName }}ToldataClientMock) Return{{ .Name }}(resp *{{ goType $OutputType }}, err error) {
+	m.Script("{{ .Name }}", &mock{{ $ServiceName }}{{ .Name }}Result{response: resp, err: err})
+}
+
+func (m *{{ $ServiceName }}ToldataClientMock) {{ .Name }}(ctx context.Context) ({{ $ServiceName }}_{{ .Name }}ToldataClient, error) {
+	call := m.Record("{{ .Name }}", nil)
+	if m.{{ .Name }}Func != nil {
+		return m.{{ .Name }}Func(ctx)
+	}
+	result, ok := m.Next("{{ .Name }}")
+	if !ok {
+		return nil, toldata.ErrNotScripted
+	}
+	r := result.(*mock{{ $ServiceName }}{{ .Name }}Result)
+	return &{{ $ServiceName }}_{{ .Name }}ToldataClientMock{mock: &m.Mock, call: call, response: r.response, err: r.err}, nil
+}
+{{ else if .Event }}
+// ReturnPublish{{ .Name }} scripts the error of Publish{{ .Name }}, events are published without error by default
+func (m *{{ $ServiceName }}ToldataClientMock) ReturnPublish{{ .Name }}(err error) {
+	m.Script("Publish{{ .Name }}", &mock{{ $ServiceName }}{{ .Name }}Result{err: err})
+}
+
+func (m *{{ $ServiceName }}ToldataClientMock) Publish{{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) error {
+	m.Record("Publish{{ .Name }}", req)
+	if m.Publish{{ .Name }}Func != nil {
+		return m.Publish{{ .Name }}Func(ctx, req)
+	}
+	result, ok := m.Next("Publish{{ .Name }}")
+	if !ok {
+		return nil
+	}
+	return result.(*mock{{ $ServiceName }}{{ .Name }}Result).err
+}
+{{ else }}
+// Return{{ .Name }} scripts the result of {{ .Name }}
+func (m *{{ $ServiceName }}ToldataClientMock) Return{{ .Name }}(resp *{{ goType $OutputType }}, err error) {
+	m.Script("{{ .Name }}", &mock{{ $ServiceName }}{{ .Name }}Result{response: resp, err: err})
+}
+
+func (m *{{ $ServiceName }}ToldataClientMock) {{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error) {
+	m.Record("{{ .Name }}", req)
+	if m.{{ .Name }}Func != nil {
+		return m.{{ .Name }}Func(ctx, req)
+	}
+	result, ok := m.Next("{{ .Name }}")
+	if !ok {
+		return nil, toldata.ErrNotScripted
+	}
+	r := result.(*mock{{ $ServiceName }}{{ .Name }}Result)
+	return r.response, r.err
+}
+{{ end }}
+{{ if or .ClientStreaming .ServerStreaming }}
+// {{ $ServiceName }}_{{ .Name }}ToldataClientMock is a {{ .Name }} stream opened by {{ $ServiceName }}ToldataClientMock
+type {{ $ServiceName }}_{{ .Name }}ToldataClientMock struct {
+	mock *toldata.Mock
+	call *toldata.MockCall
+{{ if .ServerStreaming }}	responses []*{{ goType $OutputType }}
+{{ end }}	response *{{ goType $OutputType }}
+	err error
+}
+{{ if .ClientStreaming }}
+// Send records the message in the call of the stream
+func (s *{{ $ServiceName }}_{{ .Name }}ToldataClientMock) Send(req *{{ goType $InputType }}) error {
+	if req == nil {
+		return toldata.ErrEmptyRequest
+	}
+	s.mock.RecordSent(s.call, req)
+	return nil
+}
+{{ end }}{{ if .ServerStreaming }}
+func (s *{{ $ServiceName }}_{{ .Name }}ToldataClientMock) Receive() (*{{ goType $OutputType }}, error) {
+	if len(s.responses) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, {{ pkg "io" }}.EOF
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
 {{ end }}
+func (s *{{ $ServiceName }}_{{ .Name }}ToldataClientMock) Done() (*{{ goType $OutputType }}, error) {
+	return s.response, s.err
+}
+{{ end }}{{ end }}{{ end }}
+
+{{ if $.Server }}
+// {{ $ServiceName }}ToldataMock is a fake {{ $ServiceName }}ToldataInterface. It records the calls and
+// returns the results scripted with the Return methods, or those of the Func fields when set.
+type {{ $ServiceName }}ToldataMock struct {
+	toldata.Mock
+
+	ToldataHealthCheckFunc func(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error)
+{{ range .Methods }}{{ $InputType := .InputType }}{{ $OutputType := .OutputType }}{{ if .ServerStreaming }}
+	{{ .Name }}Func func(req *{{ goType $InputType }}, stream {{ $ServiceName }}_{{ .Name }}ToldataServer) error{{ else if .ClientStreaming }}
+	{{ .Name }}Func func(stream {{ $ServiceName }}_{{ .Name }}ToldataServer){{ else }}
+	{{ .Name }}Func func(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error){{ end }}{{ end }}
+}
 
+var _ {{ $ServiceName }}ToldataInterface = (*{{ $ServiceName }}ToldataMock)(nil)
 
+func (m *{{ $ServiceName }}ToldataMock) ToldataHealthCheck(ctx context.Context, req *{{ runtime "Empty" }}) (*{{ runtime "ToldataHealthCheckInfo" }}, error) {
+	m.Record("ToldataHealthCheck", req)
+	if m.ToldataHealthCheckFunc != nil {
+		return m.ToldataHealthCheckFunc(ctx, req)
+	}
+	return &{{ runtime "ToldataHealthCheckInfo" }}{}, nil
+}
+{{ range .Methods }}{{ $InputType := .InputType }}{{ $OutputType := .OutputType }}
+{{ if .ServerStreaming }}
+// Return{{ .Name }} scripts the responses sent on a {{ .Name }} stream before returning err
+func (m *{{ $ServiceName }}ToldataMock) Return{{ .Name }}(responses []*{{ goType $OutputType }}, err error) {
+	m.Script("{{ .Name }}", &mock{{ $ServiceName }}{{ .Name }}Result{responses: responses, err: err})
+}
+
+func (m *{{ $ServiceName }}ToldataMock) {{ .Name }}(req *{{ goType $InputType }}, stream {{ $ServiceName }}_{{ .Name }}ToldataServer) error {
+	m.Record("{{ .Name }}", req)
+	if m.{{ .Name }}Func != nil {
+		return m.{{ .Name }}Func(req, stream)
+	}
+	result, ok := m.Next("{{ .Name }}")
+	if !ok {
+		return toldata.ErrNotScripted
+	}
+	r := result.(*mock{{ $ServiceName }}{{ .Name }}Result)
+	for _, resp := range r.responses {
+		err := stream.Send(resp)
+		if err != nil {
+			return err
+		}
+	}
+	return r.err
+}
+{{ else if .ClientStreaming }}
+// Return{{ .Name }} scripts the response of a {{ .Name }} stream, given once the client is done
+func (m *{{ $ServiceName }}ToldataMock) Return{{ .Name }}(resp *{{ goType $OutputType }}, err error) {
+	m.Script("{{ .Name }}", &mock{{ $ServiceName }}{{ .Name }}Result{response: resp, err: err})
+}
+
+func (m *{{ $ServiceName }}ToldataMock) {{ .Name }}(stream {{ $ServiceName }}_{{ .Name }}ToldataServer) {
+	call := m.Record("{{ .Name }}", nil)
+	if m.{{ .Name }}Func != nil {
+		m.{{ .Name }}Func(stream)
+		return
+	}
+	for {
+		req, err := stream.Receive()
+		if err == {{ pkg "io" }}.EOF {
+			break
+		}
+		if err != nil {
+			stream.Error(err)
+			return
+		}
+		m.RecordSent(call, req)
+	}
+	result, ok := m.Next("{{ .Name }}")
+	if !ok {
+		stream.Error(toldata.ErrNotScripted)
+		return
+	}
+	r := result.(*mock{{ $ServiceName }}{{ .Name }}Result)
+	if r.err != nil {
+		stream.Error(r.err)
+		return
+	}
+	err := stream.Done(r.response)
+	if err != nil {
+		stream.Error(err)
+	}
+}
+{{ else }}
+// Return{{ .Name }} scripts the result of {{ .Name }}
+func (m *{{ $ServiceName }}ToldataMock) Return{{ .Name }}(resp *{{ goType $OutputType }}, err error) {
+	m.Script("{{ .Name }}", &mock{{ $ServiceName }}{{ .Name }}Result{response: resp, err: err})
+}
+
+func (m *{{ $ServiceName }}ToldataMock) {{ .Name }}(ctx context.Context, req *{{ goType $InputType }}) (*{{ goType $OutputType }}, error) {
+	m.Record("{{ .Name }}", req)
+	if m.{{ .Name }}Func != nil {
+		return m.{{ .Name }}Func(ctx, req)
+	}
+	result, ok := m.Next("{{ .Name }}")
+	if !ok {
+		return nil, toldata.ErrNotScripted
+	}
+	r := result.(*mock{{ $ServiceName }}{{ .Name }}Result)
+	return r.response, r.err
+}
+{{ end }}{{ end }}{{ end }}
+{{ end }}
 `
 )
@@ -0,0 +1,232 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	"github.com/gogo/protobuf/protoc-gen-gogo/generator"
+)
+
+const (
+	toldataImportPath   = "github.com/citradigital/toldata"
+	toldatapbImportPath = toldataImportPath + "/toldatapb"
+	gogoTypesPath       = "github.com/gogo/protobuf/types"
+	golangTypesPath     = "google.golang.org/protobuf/types"
+)
+
+// wellKnownPackages are the Go packages of the google/protobuf files, this
+// module uses the gogo implementation of the well-known types
+var wellKnownPackages = map[string]string{
+	"google/protobuf/descriptor.proto": "github.com/gogo/protobuf/protoc-gen-gogo/descriptor",
+}
+
+// golangWellKnownPackages are the packages of the google/protobuf files with
+// messages=golang, other files are in google.golang.org/protobuf/types/known
+var golangWellKnownPackages = map[string]string{
+	"google/protobuf/descriptor.proto":      golangTypesPath + "/descriptorpb",
+	"google/protobuf/compiler/plugin.proto": golangTypesPath + "/pluginpb",
+}
+
+// reservedImports are the packages imported by the templates
+var reservedImports = map[string]string{
+	"context":      "context",
+	"connectivity": "google.golang.org/grpc/connectivity",
+	"codes":        "google.golang.org/grpc/codes",
+	"errors":       "errors",
+	"grpc":         "google.golang.org/grpc",
+	"http":         "net/http",
+	"io":           "io",
+	"nats":         "github.com/nats-io/nats.go",
+	"proto":        "github.com/gogo/protobuf/proto",
+	"status":       "google.golang.org/grpc/status",
+	"toldata":      toldataImportPath,
+}
+
+// goType is the Go type generated for a proto message
+type goType struct {
+	ImportPath  string
+	PackageName string
+	Name        string
+}
+
+// typeIndex resolves the fully qualified proto names of all messages in a
+// CodeGeneratorRequest to their Go types
+type typeIndex map[string]goType
+
+func newTypeIndex(params *parameters, files []*descriptor.FileDescriptorProto) (typeIndex, error) {
+	index := make(typeIndex)
+	for _, file := range files {
+		importPath, packageName, err := params.typesPackage(file)
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := "."
+		if file.GetPackage() != "" {
+			prefix += file.GetPackage() + "."
+		}
+
+		var add func(scope string, names []string, messages []*descriptor.DescriptorProto)
+		add = func(scope string, names []string, messages []*descriptor.DescriptorProto) {
+			for _, message := range messages {
+				nested := append(names[:len(names):len(names)], message.GetName())
+				index[scope+message.GetName()] = goType{
+					ImportPath:  importPath,
+					PackageName: packageName,
+					Name:        generator.CamelCaseSlice(nested),
+				}
+				add(scope+message.GetName()+".", nested, message.NestedType)
+			}
+		}
+		add(prefix, nil, file.MessageType)
+	}
+	return index, nil
+}
+
+// typesPackage is goPackage for the files whose types are only referenced.
+// Files without any go_package are only an error when one of their types is used.
+func (p *parameters) typesPackage(file *descriptor.FileDescriptorProto) (string, string, error) {
+	name := file.GetName()
+	goPackage := file.GetOptions().GetGoPackage()
+	if _, ok := p.ImportMap[name]; !ok {
+		switch {
+		case strings.HasPrefix(name, "google/protobuf/"):
+			importPath := p.wellKnownPackage(name)
+			return importPath, path.Base(importPath), nil
+		case file.GetPackage() == "cdl.toldata" && !strings.Contains(goPackage, "/"):
+			importPath := p.runtimePackage()
+			return importPath, path.Base(importPath), nil
+		case goPackage == "":
+			return "", "", nil
+		}
+	}
+	return p.goPackage(file)
+}
+
+func (p *parameters) wellKnownPackage(name string) string {
+	if p.Messages == messagesGolang {
+		if importPath, ok := golangWellKnownPackages[name]; ok {
+			return importPath
+		}
+		base := strings.TrimSuffix(path.Base(name), ".proto")
+		return golangTypesPath + "/known/" + strings.Replace(base, "_", "", -1) + "pb"
+	}
+	if importPath, ok := wellKnownPackages[name]; ok {
+		return importPath
+	}
+	return gogoTypesPath
+}
+
+// imports qualifies the Go types used by a generated file and collects
+// the imports they need
+type imports struct {
+	index      typeIndex
+	importPath string
+	runtime    string
+	aliases    map[string]string
+	used       map[string]string
+	err        error
+}
+
+func newImports(index typeIndex, importPath, runtime string) *imports {
+	return &imports{
+		index:      index,
+		importPath: importPath,
+		runtime:    runtime,
+		aliases:    make(map[string]string),
+		used:       make(map[string]string),
+	}
+}
+
+// goType returns the Go type of a fully qualified proto name, such as
+// .cdl.toldata.Empty or .pkg.Outer.Inner
+func (im *imports) goType(name string) string {
+	t, ok := im.index[name]
+	if !ok || t.ImportPath == "" {
+		if im.err == nil {
+			im.err = fmt.Errorf("unable to resolve the Go type of %s, add go_package or M<file>=<path>", strings.TrimPrefix(name, "."))
+		}
+		return "invalid"
+	}
+	if t.ImportPath == im.importPath {
+		return t.Name
+	}
+	if !strings.Contains(t.ImportPath, "/") {
+		if im.err == nil {
+			im.err = fmt.Errorf("%s is in Go package %s which can not be imported, add M<file>=<path>", strings.TrimPrefix(name, "."), t.ImportPath)
+		}
+		return "invalid"
+	}
+	return im.alias(t.ImportPath, t.PackageName) + "." + t.Name
+}
+
+// runtimeType returns a message of toldata.proto, such as ErrorMessage, in
+// the flavour of the generated messages
+func (im *imports) runtimeType(name string) string {
+	return im.pkg(im.runtime) + "." + name
+}
+
+// pkg imports a package used by the template only in some files
+func (im *imports) pkg(importPath string) string {
+	return im.alias(importPath, path.Base(importPath))
+}
+
+func (im *imports) alias(importPath, packageName string) string {
+	if alias, ok := im.aliases[importPath]; ok {
+		im.used[importPath] = alias
+		return alias
+	}
+
+	alias := packageName
+	if reserved, ok := reservedImports[alias]; !ok || reserved != importPath {
+		for i := 1; im.taken(alias, importPath); i++ {
+			alias = packageName + strconv.Itoa(i)
+		}
+	}
+	im.aliases[importPath] = alias
+	im.used[importPath] = alias
+	return alias
+}
+
+func (im *imports) taken(alias, importPath string) bool {
+	if _, ok := reservedImports[alias]; ok {
+		return true
+	}
+	for path, other := range im.aliases {
+		if other == alias && path != importPath {
+			return true
+		}
+	}
+	return false
+}
+
+// lines returns the imports of the used types, toldata is always imported
+// by the templates
+func (im *imports) lines() string {
+	var result []string
+	for importPath, alias := range im.used {
+		if importPath == toldataImportPath {
+			continue
+		}
+		result = append(result, alias+" "+strconv.Quote(importPath))
+	}
+	sort.Strings(result)
+	return strings.Join(result, "\n\t")
+}
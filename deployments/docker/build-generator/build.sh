@@ -5,3 +5,4 @@ apk add --update git binutils
 cd /src
 CGO_ENABLED=0 GOOS=linux go build -o /build/protoc-gen-toldata /src/cmd/toldata-gen/*.go 
 strip /build/protoc-gen-toldata
+GOBIN=/build go install google.golang.org/protobuf/cmd/protoc-gen-go@v1.33.0
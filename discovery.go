@@ -0,0 +1,292 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+)
+
+// MetadataService is the metadata of a registration holding the fully
+// qualified name of the proto service
+const MetadataService = "toldata.service"
+
+var discoveryNameRegexp = regexp.MustCompile(`^[A-Za-z0-9\-_]+$`)
+
+// DiscoveryOptions registers a server with the NATS micro protocol, so that
+// it answers on the $SRV.PING, $SRV.INFO and $SRV.STATS subjects
+type DiscoveryOptions struct {
+	// Name is the name of the service, the proto name with the dots
+	// replaced by underscores by default
+	Name string
+	// Version is the semantic version of the service, the version served
+	// by the server by default and 0.0.0 when it has none
+	Version     string
+	Description string
+	Metadata    map[string]string
+}
+
+// DiscoveryEndpoint is an endpoint advertised by a registration
+type DiscoveryEndpoint struct {
+	// Name is the name of the method
+	Name       string
+	Subject    string
+	QueueGroup string
+}
+
+// Registration answers the NATS micro requests of a server, with the
+// statistics of its endpoints
+type Registration struct {
+	identity    micro.ServiceIdentity
+	description string
+	started     time.Time
+
+	mutex     sync.Mutex
+	endpoints []*micro.EndpointStats
+	// versions counts the calls of each endpoint by version constraint
+	versions map[string]map[string]int
+	// aliases counts the calls of each endpoint received on an alias
+	aliases       map[string]map[string]int
+	subscriptions []*nats.Subscription
+}
+
+// endpointData is the data of the endpoint statistics
+type endpointData struct {
+	Versions map[string]int `json:"versions,omitempty"`
+	Aliases  map[string]int `json:"aliases,omitempty"`
+}
+
+// Register advertises the endpoints of a service, named serviceName in the
+// proto files, until the registration is closed. The instance ID is the one
+// of the bus.
+func (bus *Bus) Register(options DiscoveryOptions, serviceName string, endpoints ...DiscoveryEndpoint) (*Registration, error) {
+	name := options.Name
+	if name == "" {
+		name = natsName(serviceName)
+	}
+	if !discoveryNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid service name %q", name)
+	}
+	version := options.Version
+	if version == "" {
+		version = "0.0.0"
+	}
+
+	metadata := map[string]string{MetadataService: serviceName}
+	for key, value := range options.Metadata {
+		metadata[key] = value
+	}
+
+	r := &Registration{
+		identity: micro.ServiceIdentity{
+			Name:     name,
+			ID:       bus.InstanceID(),
+			Version:  version,
+			Metadata: metadata,
+		},
+		description: options.Description,
+		started:     time.Now().UTC(),
+		versions:    make(map[string]map[string]int),
+		aliases:     make(map[string]map[string]int),
+	}
+	for _, e := range endpoints {
+		r.endpoints = append(r.endpoints, &micro.EndpointStats{
+			Name:       e.Name,
+			Subject:    e.Subject,
+			QueueGroup: e.QueueGroup,
+		})
+	}
+
+	verbs := map[micro.Verb]func() interface{}{
+		micro.PingVerb:  func() interface{} { return r.Ping() },
+		micro.InfoVerb:  func() interface{} { return r.Info() },
+		micro.StatsVerb: func() interface{} { return r.Stats() },
+	}
+	for verb, value := range verbs {
+		value := value
+		handler := func(m *nats.Msg) {
+			data, err := json.Marshal(value())
+			if err == nil {
+				m.Respond(data)
+			}
+		}
+		// Every service, the services with the name and this instance
+		for _, kind := range [][2]string{{"", ""}, {name, ""}, {name, r.identity.ID}} {
+			subject, err := micro.ControlSubject(verb, kind[0], kind[1])
+			if err == nil {
+				var sub *nats.Subscription
+				sub, err = bus.Connection.Subscribe(subject, handler)
+				if err == nil {
+					r.subscriptions = append(r.subscriptions, sub)
+				}
+			}
+			if err != nil {
+				r.Close()
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+// Track starts the call m of an endpoint, the returned function records its
+// result. It does nothing on a nil registration.
+func (r *Registration) Track(endpoint string, m *nats.Msg) func(err error) {
+	if r == nil {
+		return func(error) {}
+	}
+	start := time.Now()
+	constraint := m.Header.Get(HeaderVersion)
+	return func(err error) {
+		elapsed := time.Since(start)
+
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		for _, e := range r.endpoints {
+			if e.Name != endpoint {
+				continue
+			}
+			e.NumRequests++
+			e.ProcessingTime += elapsed
+			e.AverageProcessingTime = e.ProcessingTime / time.Duration(e.NumRequests)
+			if err != nil {
+				e.NumErrors++
+				e.LastError = err.Error()
+			}
+		}
+		if constraint != "" {
+			if r.versions[endpoint] == nil {
+				r.versions[endpoint] = make(map[string]int)
+			}
+			r.versions[endpoint][constraint]++
+		}
+	}
+}
+
+// Ping returns the reply to $SRV.PING
+func (r *Registration) Ping() micro.Ping {
+	return micro.Ping{ServiceIdentity: r.identity, Type: micro.PingResponseType}
+}
+
+// Info returns the reply to $SRV.INFO
+func (r *Registration) Info() micro.Info {
+	info := micro.Info{
+		ServiceIdentity: r.identity,
+		Type:            micro.InfoResponseType,
+		Description:     r.description,
+		Endpoints:       []micro.EndpointInfo{},
+	}
+	for _, e := range r.endpoints {
+		info.Endpoints = append(info.Endpoints, micro.EndpointInfo{
+			Name:       e.Name,
+			Subject:    e.Subject,
+			QueueGroup: e.QueueGroup,
+		})
+	}
+	return info
+}
+
+// Stats returns the reply to $SRV.STATS
+func (r *Registration) Stats() micro.Stats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stats := micro.Stats{
+		ServiceIdentity: r.identity,
+		Type:            micro.StatsResponseType,
+		Started:         r.started,
+		Endpoints:       []*micro.EndpointStats{},
+	}
+	for _, e := range r.endpoints {
+		copied := *e
+		data := endpointData{Versions: r.versions[e.Name], Aliases: r.aliases[e.Name]}
+		if len(data.Versions) > 0 || len(data.Aliases) > 0 {
+			copied.Data, _ = json.Marshal(data)
+		}
+		stats.Endpoints = append(stats.Endpoints, &copied)
+	}
+	return stats
+}
+
+// Close stops answering the NATS micro requests. It does nothing on a nil
+// registration.
+func (r *Registration) Close() error {
+	if r == nil {
+		return nil
+	}
+	var result error
+	for _, sub := range r.subscriptions {
+		err := sub.Unsubscribe()
+		if err != nil && !errors.Is(err, nats.ErrConnectionClosed) && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// Discover returns the running instances of the services registered with the
+// NATS micro protocol, of every service when name is empty. The replies are
+// collected until ctx is done, for one second when it has no deadline.
+func Discover(ctx context.Context, bus *Bus, name string) ([]micro.Info, error) {
+	subject, err := micro.ControlSubject(micro.InfoVerb, name, "")
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := WithDefaultTimeout(ctx, time.Second)
+	defer cancel()
+
+	inbox := bus.Connection.NewRespInbox()
+	sub, err := bus.Connection.SubscribeSync(inbox)
+	if err != nil {
+		return nil, RequestError(subject, err)
+	}
+	defer sub.Unsubscribe()
+
+	err = bus.Connection.PublishRequest(subject, inbox, nil)
+	if err != nil {
+		return nil, RequestError(subject, err)
+	}
+
+	var instances []micro.Info
+	for {
+		m, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			break
+		}
+		var info micro.Info
+		if json.Unmarshal(m.Data, &info) == nil {
+			instances = append(instances, info)
+		}
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return instances, RequestError(subject, ctx.Err())
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Name != instances[j].Name {
+			return instances[i].Name < instances[j].Name
+		}
+		return instances[i].ID < instances[j].ID
+	})
+	return instances, nil
+}
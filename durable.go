@@ -0,0 +1,329 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+)
+
+const (
+	// HeaderError is the error of the last delivery of a dead letter
+	HeaderError = "Toldata-Error"
+	// HeaderDeliveries is the number of deliveries of a dead letter
+	HeaderDeliveries = "Toldata-Deliveries"
+
+	durablePublishWait = 5 * time.Second
+	durableFetchWait   = time.Second
+)
+
+// DurableOptions configures how the servers consume the calls of the
+// methods with the durable option
+type DurableOptions struct {
+	// Stream is the JetStream stream storing the calls, named after the
+	// subject of the service and the scheme of each subject by default, see
+	// SubjectScheme.StreamName
+	Stream string
+	// MaxDeliver is how many times a call is delivered before it is moved
+	// to the dead letter subject, 5 by default
+	MaxDeliver int
+	// Backoff are the delays before a failed call is delivered again, the
+	// last one is used once all of them are, 1s by default
+	Backoff []time.Duration
+	// AckWait is how long a call is left to a server before it is delivered
+	// again, e.g. when the server crashed, 30s by default
+	AckWait time.Duration
+	// Batch is how many calls a server fetches at once, 1 by default
+	Batch int
+}
+
+func (o DurableOptions) maxDeliver() int {
+	if o.MaxDeliver <= 0 {
+		return 5
+	}
+	return o.MaxDeliver
+}
+
+func (o DurableOptions) backoff(delivered uint64) time.Duration {
+	if len(o.Backoff) == 0 {
+		return time.Second
+	}
+	if delivered > uint64(len(o.Backoff)) {
+		delivered = uint64(len(o.Backoff))
+	}
+	return o.Backoff[delivered-1]
+}
+
+func (o DurableOptions) ackWait() time.Duration {
+	if o.AckWait <= 0 {
+		return 30 * time.Second
+	}
+	return o.AckWait
+}
+
+func (o DurableOptions) batch() int {
+	if o.Batch <= 0 {
+		return 1
+	}
+	return o.Batch
+}
+
+// DurableHandler handles a call of a durable method, the call is acknowledged
+// when it returns nil and delivered again otherwise
+type DurableHandler func(m *nats.Msg) error
+
+// DurableStreamName returns the default name of the stream of a service
+func DurableStreamName(serviceSubject string) string {
+	return natsName(serviceSubject)
+}
+
+// DeadLetterSubject returns the subject to which the calls of a durable
+// method are moved once they are delivered DurableOptions.MaxDeliver times.
+// It is stored in the stream of the service.
+func DeadLetterSubject(subject string) string {
+	return subject + "_Dead_"
+}
+
+// natsName replaces the characters not allowed in stream and consumer names
+func natsName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '/', '\\', '*', '>', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+type messageIDKey struct{}
+
+// WithMessageID sets the ID of the durable call made with ctx. The calls
+// made again with the same ID within two minutes are stored once.
+func WithMessageID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, id)
+}
+
+// MessageIDFromContext returns the ID set by WithMessageID
+func MessageIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(messageIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// PublishDurable stores a call in the stream of a durable method. It is
+// published again with the same message ID while the stream is not
+// available, up to Configuration.Retries times.
+func (bus *Bus) PublishDurable(ctx context.Context, subject string, data []byte) error {
+	js, err := bus.Connection.JetStream()
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if p, ok := PeerFromContext(ctx); ok {
+		setPeerHeader(msg.Header, p)
+	}
+	setCredentialHeader(ctx, msg.Header, bus.Configuration.Credential)
+	id, ok := MessageIDFromContext(ctx)
+	if !ok {
+		id = nuid.Next()
+	}
+	msg.Header.Set(nats.MsgIdHdr, id)
+
+	ctx, cancel := WithDefaultTimeout(ctx, durablePublishWait)
+	defer cancel()
+	return bus.retry(ctx, func() error {
+		_, err := js.PublishMsg(msg, nats.Context(ctx))
+		return err
+	})
+}
+
+// EnsureDurableStream creates the stream or adds the subjects to it
+func (bus *Bus) EnsureDurableStream(name string, subjects ...string) error {
+	js, err := bus.Connection.JetStream()
+	if err != nil {
+		return err
+	}
+
+	info, err := js.StreamInfo(name)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:       name,
+			Subjects:   subjects,
+			Retention:  nats.WorkQueuePolicy,
+			Duplicates: 2 * time.Minute,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	config := info.Config
+	missing := false
+	for _, subject := range subjects {
+		found := false
+		for _, existing := range config.Subjects {
+			found = found || existing == subject
+		}
+		if !found {
+			config.Subjects = append(config.Subjects, subject)
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+	_, err = js.UpdateStream(&config)
+	return err
+}
+
+// SubscribeDurable consumes the calls of a durable method received on a
+// subject of ServerSubjects with a durable pull consumer shared by the
+// servers of the queue group. The stream and the consumer are created when
+// needed. The calls are fetched until the returned subscription is
+// unsubscribed.
+func (bus *Bus) SubscribeDurable(server ServerSubject, method string, options DurableOptions, handler DurableHandler) (*nats.Subscription, error) {
+	js, err := bus.Connection.JetStream()
+	if err != nil {
+		return nil, err
+	}
+
+	subject := server.Subject
+	stream := options.Stream
+	if stream == "" {
+		stream = server.Stream
+	}
+	err = bus.EnsureDurableStream(stream, subject, DeadLetterSubject(subject))
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := natsName(server.QueueGroup + "_" + method)
+	// MaxDeliver is enforced here so that the calls are moved to the dead
+	// letter subject instead of being dropped
+	config := &nats.ConsumerConfig{
+		Durable:       consumer,
+		FilterSubject: subject,
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       options.ackWait(),
+		MaxDeliver:    -1,
+	}
+	info, err := js.ConsumerInfo(stream, consumer)
+	switch {
+	case errors.Is(err, nats.ErrConsumerNotFound):
+		_, err = js.AddConsumer(stream, config)
+	case err == nil:
+		filters := info.Config.FilterSubjects
+		if info.Config.FilterSubject != "" {
+			filters = []string{info.Config.FilterSubject}
+		}
+		missing := true
+		for _, filter := range filters {
+			missing = missing && filter != subject
+		}
+		if missing {
+			// The subjects of the queue group stored in the same stream, such
+			// as the ones of schemes differing by their separator, share the
+			// consumer
+			config.FilterSubject, config.FilterSubjects = "", append(filters, subject)
+		} else {
+			config.FilterSubject, config.FilterSubjects = info.Config.FilterSubject, info.Config.FilterSubjects
+		}
+		if missing || info.Config.AckWait != config.AckWait {
+			// The subjects or the options changed since the consumer was created
+			_, err = js.UpdateConsumer(stream, config)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The consumer is bound so that it is kept when the server unsubscribes
+	sub, err := js.PullSubscribe(subject, consumer, nats.Bind(stream, consumer))
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for sub.IsValid() {
+			msgs, err := sub.Fetch(options.batch(), nats.MaxWait(durableFetchWait))
+			if err != nil {
+				if !errors.Is(err, nats.ErrTimeout) && sub.IsValid() {
+					time.Sleep(durableFetchWait)
+				}
+				continue
+			}
+			for _, m := range msgs {
+				bus.handleDurable(js, options, m, handler)
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+func (bus *Bus) handleDurable(js nats.JetStreamContext, options DurableOptions, m *nats.Msg, handler DurableHandler) {
+	meta, err := m.Metadata()
+	if err != nil {
+		m.Term()
+		return
+	}
+
+	maxDeliver := uint64(options.maxDeliver())
+	if meta.NumDelivered > maxDeliver {
+		// The servers handling the call did not survive it
+		bus.deadLetter(js, m, meta.NumDelivered, errors.New("no server acknowledged the call"))
+		return
+	}
+
+	err = handler(m)
+	if err == nil {
+		m.Ack()
+		return
+	}
+	if meta.NumDelivered >= maxDeliver {
+		bus.deadLetter(js, m, meta.NumDelivered, err)
+		return
+	}
+	m.NakWithDelay(options.backoff(meta.NumDelivered))
+}
+
+// deadLetter moves a call to the dead letter subject of the subject on which
+// it was made, along with its headers
+func (bus *Bus) deadLetter(js nats.JetStreamContext, m *nats.Msg, delivered uint64, cause error) {
+	msg := nats.NewMsg(DeadLetterSubject(m.Subject))
+	msg.Data = m.Data
+	for key, values := range m.Header {
+		msg.Header[key] = values
+	}
+	// The ID is already known to the stream
+	msg.Header.Del(nats.MsgIdHdr)
+	msg.Header.Set(HeaderError, cause.Error())
+	msg.Header.Set(HeaderDeliveries, strconv.FormatUint(delivered, 10))
+
+	_, err := js.PublishMsg(msg)
+	if err != nil {
+		// Kept in the stream until the dead letter subject is available
+		m.Nak()
+		return
+	}
+	m.Term()
+}
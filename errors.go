@@ -0,0 +1,148 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/protobuf/ptypes/any"
+	nats "github.com/nats-io/nats.go"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrEmptyRequest is returned when a method is called with a nil request
+	ErrEmptyRequest = &Error{Message: "empty-request", Code: codes.InvalidArgument}
+	// ErrStreamCanceled is returned when a stream is read after it was canceled
+	ErrStreamCanceled = &Error{Message: "canceled", Code: codes.Canceled}
+)
+
+// Error is returned by clients when a call fails, either in the remote
+// handler or on the way to it. It carries a gRPC status code so the gRPC
+// gateway can report the failure faithfully.
+type Error struct {
+	// Message describes the error
+	Message string
+	// Code is the gRPC status code of the error
+	Code codes.Code
+	// Details are the details attached to the gRPC status of the error
+	Details []*types.Any
+	// BusID identifies the bus which reported the error
+	BusID string
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the transport error which caused the failure, if any
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// GRPCStatus converts the error into a gRPC status
+func (e *Error) GRPCStatus() *status.Status {
+	st := &spb.Status{
+		Code:    int32(e.Code),
+		Message: e.Message,
+	}
+	for _, detail := range e.Details {
+		st.Details = append(st.Details, &any.Any{TypeUrl: detail.TypeUrl, Value: detail.Value})
+	}
+	return status.FromProto(st)
+}
+
+// Err converts an ErrorMessage received from a server into an error.
+// The end of a stream is reported as io.EOF.
+func (m *ErrorMessage) Err() error {
+	if m.Code == 0 && m.ErrorMessage == io.EOF.Error() {
+		return io.EOF
+	}
+
+	code := codes.Code(m.Code)
+	if code == codes.OK {
+		code = codes.Unknown
+	}
+
+	return &Error{
+		Message: m.ErrorMessage,
+		Code:    code,
+		Details: m.Details,
+		BusID:   m.BusID,
+	}
+}
+
+// NewErrorMessage creates the ErrorMessage sent to the caller when a handler
+// fails. Errors with a gRPC status keep their code and details.
+func NewErrorMessage(busID string, err error) *ErrorMessage {
+	msg := &ErrorMessage{
+		ErrorMessage: err.Error(),
+		Timestamp:    time.Now().UnixNano(),
+		BusID:        busID,
+	}
+
+	if st, ok := status.FromError(err); ok {
+		msg.ErrorMessage = st.Message()
+		msg.Code = int32(st.Code())
+		for _, detail := range st.Proto().Details {
+			msg.Details = append(msg.Details, &types.Any{TypeUrl: detail.TypeUrl, Value: detail.Value})
+		}
+	}
+
+	return msg
+}
+
+// InternalMethodError is returned by gateways for the methods which are
+// only served on the bus
+func InternalMethodError(method string) error {
+	return &Error{Message: method + " is internal", Code: codes.Unimplemented}
+}
+
+// UnimplementedMethodError is returned by the methods of the generated
+// Unimplemented<Service>ToldataServer
+func UnimplementedMethodError(method string) error {
+	return &Error{Message: method + " is not implemented", Code: codes.Unimplemented}
+}
+
+// IsUnimplemented reports whether err comes from a method which is not
+// implemented or not exposed
+func IsUnimplemented(err error) bool {
+	return status.Code(err) == codes.Unimplemented
+}
+
+// RequestError converts a failure to deliver a request or to get its
+// reply into an Error with the matching gRPC status code
+func RequestError(functionName string, err error) error {
+	code := codes.Unavailable
+	switch {
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, nats.ErrTimeout):
+		code = codes.DeadlineExceeded
+	}
+
+	return &Error{
+		Message: functionName + ":" + err.Error(),
+		Code:    code,
+		cause:   err,
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	nats "github.com/nats-io/nats.go"
+)
+
+// EventDelivery tells which servers receive the events of the methods with
+// the event option
+type EventDelivery int
+
+const (
+	// QueueDelivery delivers each event to one server of the queue group
+	QueueDelivery EventDelivery = iota
+	// BroadcastDelivery delivers each event to every server
+	BroadcastDelivery
+)
+
+// SubscribeEvents subscribes the handler of an event method
+func (bus *Bus) SubscribeEvents(subject, queueGroup string, delivery EventDelivery, handler nats.MsgHandler) (*nats.Subscription, error) {
+	if delivery == BroadcastDelivery {
+		return bus.Connection.Subscribe(subject, handler)
+	}
+	return bus.Connection.QueueSubscribe(subject, queueGroup, handler)
+}
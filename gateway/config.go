@@ -0,0 +1,190 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"time"
+
+	"github.com/citradigital/toldata"
+	nats "github.com/nats-io/nats.go"
+)
+
+// Duration is a time.Duration written as a string such as "5s" in the configuration
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+
+	value, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Config describes what a gateway exposes and how it reaches NATS
+type Config struct {
+	// Descriptors lists FileDescriptorSet files, created with
+	// protoc --include_imports --descriptor_set_out=
+	Descriptors []string `json:"descriptors"`
+	// Services lists the fully qualified names of the services to expose.
+	// All services of the descriptor sets are exposed when empty.
+	Services []string `json:"services"`
+	// TrustedProxies lists the proxies whose forwarding headers are honoured
+	TrustedProxies []string `json:"trusted_proxies"`
+
+	NATS NATSConfig `json:"nats"`
+	GRPC GRPCConfig `json:"grpc"`
+	REST RESTConfig `json:"rest"`
+}
+
+// NATSConfig describes the connection to NATS
+type NATSConfig struct {
+	URL string `json:"url"`
+	// ID is the bus ID of the gateway, reported to the services as the gateway of their callers
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Credentials   string   `json:"credentials"`
+	Token         string   `json:"token"`
+	User          string   `json:"user"`
+	Password      string   `json:"password"`
+	MaxReconnects int      `json:"max_reconnects"`
+	ReconnectWait Duration `json:"reconnect_wait"`
+	// Retries is how many times calls to idempotent methods are retried
+	// while no server is available
+	Retries    int      `json:"retries"`
+	RetryDelay Duration `json:"retry_delay"`
+	// Subjects is the subject scheme of the services, the legacy one by default
+	Subjects SubjectsConfig `json:"subjects"`
+}
+
+// SubjectsConfig describes the subject scheme, see toldata.SubjectScheme
+type SubjectsConfig struct {
+	Prefix      string `json:"prefix"`
+	Environment string `json:"environment"`
+	Separator   string `json:"separator"`
+}
+
+// GRPCConfig describes the gRPC listener. The standard health and
+// reflection services are always registered.
+type GRPCConfig struct {
+	// Listen is the listen address, gRPC is disabled when empty
+	Listen string `json:"listen"`
+}
+
+// RESTConfig describes the REST listener, see toldata.RESTOptions
+type RESTConfig struct {
+	// Listen is the listen address, REST is disabled when empty
+	Listen           string     `json:"listen"`
+	MaxBodySize      int64      `json:"max_body_size"`
+	AllowedOrigins   []string   `json:"allowed_origins"`
+	AllowedHeaders   []string   `json:"allowed_headers"`
+	AllowCredentials bool       `json:"allow_credentials"`
+	CORSMaxAge       Duration   `json:"cors_max_age"`
+	Gzip             bool       `json:"gzip"`
+	JSON             JSONConfig `json:"json"`
+}
+
+// JSONConfig controls the JSON mapping, see toldata.JSONOptions
+type JSONConfig struct {
+	EmitDefaults   bool   `json:"emit_defaults"`
+	OrigName       bool   `json:"orig_name"`
+	EnumsAsInts    bool   `json:"enums_as_ints"`
+	DiscardUnknown bool   `json:"discard_unknown"`
+	Indent         string `json:"indent"`
+}
+
+// LoadConfig reads a JSON configuration file
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks that the configuration is usable
+func (c *Config) Validate() error {
+	if len(c.Descriptors) == 0 {
+		return errors.New("no descriptor set configured")
+	}
+	if c.NATS.URL == "" {
+		return errors.New("no NATS URL configured")
+	}
+	if c.GRPC.Listen == "" && c.REST.Listen == "" {
+		return errors.New("neither a gRPC nor a REST listen address is configured")
+	}
+	return nil
+}
+
+// ServiceConfiguration returns the bus configuration of the gateway
+func (c *Config) ServiceConfiguration() toldata.ServiceConfiguration {
+	var options []nats.Option
+	if c.NATS.Name != "" {
+		options = append(options, nats.Name(c.NATS.Name))
+	}
+	if c.NATS.Credentials != "" {
+		options = append(options, nats.UserCredentials(c.NATS.Credentials))
+	}
+	if c.NATS.Token != "" {
+		options = append(options, nats.Token(c.NATS.Token))
+	}
+	if c.NATS.User != "" {
+		options = append(options, nats.UserInfo(c.NATS.User, c.NATS.Password))
+	}
+	if c.NATS.MaxReconnects != 0 {
+		options = append(options, nats.MaxReconnects(c.NATS.MaxReconnects))
+	}
+	if c.NATS.ReconnectWait != 0 {
+		options = append(options, nats.ReconnectWait(time.Duration(c.NATS.ReconnectWait)))
+	}
+
+	return toldata.ServiceConfiguration{
+		URL:        c.NATS.URL,
+		ID:         c.NATS.ID,
+		Options:    options,
+		Retries:    c.NATS.Retries,
+		RetryDelay: time.Duration(c.NATS.RetryDelay),
+		Subjects: toldata.SubjectScheme{
+			Prefix:      c.NATS.Subjects.Prefix,
+			Environment: c.NATS.Subjects.Environment,
+			Separator:   c.NATS.Subjects.Separator,
+		},
+	}
+}
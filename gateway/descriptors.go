@@ -0,0 +1,193 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	// The well known types are resolved from the global registry
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Descriptors holds the files loaded from descriptor sets
+type Descriptors struct {
+	Files *protoregistry.Files
+	// raw holds the serialized FileDescriptorProto of each file, for reflection
+	raw map[string][]byte
+	// names lists the files given in the sets, in order
+	names []string
+}
+
+// resolver looks files up in the loaded files first and in the global registry next
+type resolver struct {
+	files *protoregistry.Files
+}
+
+func (r resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	fd, err := r.files.FindFileByPath(path)
+	if err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	d, err := r.files.FindDescriptorByName(name)
+	if err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+// LoadDescriptors reads FileDescriptorSet files
+func LoadDescriptors(paths ...string) (*Descriptors, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		part := &descriptorpb.FileDescriptorSet{}
+		err = proto.Unmarshal(data, part)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		set.File = append(set.File, part.File...)
+	}
+
+	return NewDescriptors(set)
+}
+
+// NewDescriptors builds the descriptors of a FileDescriptorSet. Files of the
+// set which are compiled into the gateway, such as the well known types, are
+// taken from the global registry.
+func NewDescriptors(set *descriptorpb.FileDescriptorSet) (*Descriptors, error) {
+	d := &Descriptors{
+		Files: &protoregistry.Files{},
+		raw:   make(map[string][]byte),
+	}
+
+	pending := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, file := range set.File {
+		if _, ok := pending[file.GetName()]; ok {
+			continue
+		}
+		pending[file.GetName()] = file
+		d.names = append(d.names, file.GetName())
+	}
+
+	var build func(name string, seen map[string]bool) error
+	build = func(name string, seen map[string]bool) error {
+		if _, err := d.Files.FindFileByPath(name); err == nil {
+			return nil
+		}
+		file, ok := pending[name]
+		if !ok {
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(name); err == nil {
+				return nil
+			}
+			return fmt.Errorf("missing dependency %s, use protoc --include_imports", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("import cycle on %s", name)
+		}
+		seen[name] = true
+
+		// A file compiled into the gateway keeps its global descriptor, it is
+		// still registered here so that its services are found
+		fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+		if err != nil {
+			for _, dependency := range file.Dependency {
+				err := build(dependency, seen)
+				if err != nil {
+					return err
+				}
+			}
+
+			fd, err = protodesc.NewFile(file, resolver{d.Files})
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		err = d.Files.RegisterFile(fd)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+
+		raw, err := proto.Marshal(file)
+		if err != nil {
+			return err
+		}
+		d.raw[name] = raw
+		return nil
+	}
+
+	for _, name := range d.names {
+		err := build(name, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// Services returns the services declared in the loaded files
+func (d *Descriptors) Services() []protoreflect.ServiceDescriptor {
+	var result []protoreflect.ServiceDescriptor
+	for _, name := range d.names {
+		fd, err := d.Files.FindFileByPath(name)
+		if err != nil {
+			continue
+		}
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			result = append(result, services.Get(i))
+		}
+	}
+	return result
+}
+
+// FindService returns a loaded service by its fully qualified name
+func (d *Descriptors) FindService(name string) (protoreflect.ServiceDescriptor, error) {
+	desc, err := d.Files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown service %s", name)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", name)
+	}
+	return sd, nil
+}
+
+// FileDescriptor returns the serialized FileDescriptorProto of a loaded file,
+// it is a toldata.FileDescriptorLoader
+func (d *Descriptors) FileDescriptor(name string) []byte {
+	return d.raw[name]
+}
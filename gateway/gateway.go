@@ -0,0 +1,187 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gateway exposes toldata services over gRPC and REST without
+// generated code. Messages are encoded and decoded with the descriptors
+// loaded from FileDescriptorSet files.
+package gateway
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/citradigital/toldata"
+	gogoproto "github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Gateway forwards gRPC and REST calls to toldata services on NATS
+type Gateway struct {
+	Config      Config
+	Bus         *toldata.Bus
+	Descriptors *Descriptors
+	Services    []protoreflect.ServiceDescriptor
+
+	grpcOptions toldata.GRPCOptions
+	restOptions toldata.RESTOptions
+	health      *toldata.HealthServer
+}
+
+// New loads the descriptor sets of the configuration and connects to NATS
+func New(ctx context.Context, config Config) (*Gateway, error) {
+	descriptors, err := LoadDescriptors(config.Descriptors...)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gateway{
+		Config:      config,
+		Descriptors: descriptors,
+	}
+
+	if len(config.Services) == 0 {
+		g.Services = descriptors.Services()
+	}
+	for _, name := range config.Services {
+		service, err := descriptors.FindService(name)
+		if err != nil {
+			return nil, err
+		}
+		g.Services = append(g.Services, service)
+	}
+
+	proxies, err := toldata.NewTrustedProxies(config.TrustedProxies...)
+	if err != nil {
+		return nil, err
+	}
+	g.grpcOptions = toldata.GRPCOptions{TrustedProxies: proxies}
+	g.restOptions = toldata.RESTOptions{
+		JSON: toldata.JSONOptions{
+			EmitDefaults:   config.REST.JSON.EmitDefaults,
+			OrigName:       config.REST.JSON.OrigName,
+			EnumsAsInts:    config.REST.JSON.EnumsAsInts,
+			DiscardUnknown: config.REST.JSON.DiscardUnknown,
+			Indent:         config.REST.JSON.Indent,
+		},
+		MaxBodySize:      config.REST.MaxBodySize,
+		AllowedOrigins:   config.REST.AllowedOrigins,
+		AllowedHeaders:   config.REST.AllowedHeaders,
+		AllowCredentials: config.REST.AllowCredentials,
+		CORSMaxAge:       time.Duration(config.REST.CORSMaxAge),
+		Gzip:             config.REST.Gzip,
+		TrustedProxies:   proxies,
+	}
+
+	g.Bus, err = toldata.NewBus(ctx, config.ServiceConfiguration())
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// Close closes the connection to NATS
+func (g *Gateway) Close() {
+	g.Bus.Close()
+}
+
+// serviceHealth checks the health of an exposed service over the bus
+type serviceHealth struct {
+	bus     *toldata.Bus
+	service protoreflect.ServiceDescriptor
+}
+
+func (h serviceHealth) ToldataServiceName() string {
+	return string(h.service.FullName())
+}
+
+func (h serviceHealth) ToldataHealthCheck(ctx context.Context, req *toldata.Empty) (*toldata.ToldataHealthCheckInfo, error) {
+	subject := h.bus.Subject(ServiceSubject(h.service), "ToldataHealthCheck")
+	reqRaw, err := gogoproto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := h.bus.Call(ctx, subject, reqRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &toldata.ToldataHealthCheckInfo{}
+	err = gogoproto.Unmarshal(raw, info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Run serves the configured listeners until the context is canceled
+func (g *Gateway) Run(ctx context.Context) error {
+	errs := make(chan error, 2)
+
+	var grpcServer *grpc.Server
+	if g.Config.GRPC.Listen != "" {
+		lis, err := net.Listen("tcp", g.Config.GRPC.Listen)
+		if err != nil {
+			return err
+		}
+		grpcServer = g.GRPCServer()
+		log.Println("Serving gRPC on", lis.Addr())
+		go func() {
+			errs <- grpcServer.Serve(lis)
+		}()
+	}
+
+	var restServer *http.Server
+	if g.Config.REST.Listen != "" {
+		lis, err := net.Listen("tcp", g.Config.REST.Listen)
+		if err != nil {
+			if grpcServer != nil {
+				grpcServer.Stop()
+			}
+			return err
+		}
+		restServer = &http.Server{Handler: g.RESTHandler()}
+		log.Println("Serving REST on", lis.Addr())
+		go func() {
+			errs <- restServer.Serve(lis)
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	if g.health != nil {
+		g.health.Shutdown()
+	}
+	if restServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		restServer.Shutdown(shutdownCtx)
+		cancel()
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/citradigital/toldata"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// frame is a message forwarded without being decoded, the toldata wire
+// format of a message is its protobuf encoding
+type frame struct {
+	data []byte
+}
+
+// rawCodec passes frames through and encodes other messages, such as the
+// ones of the health service, with protobuf
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	if f, ok := v.(*frame); ok {
+		return f.data, nil
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("unsupported message type %T", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	if f, ok := v.(*frame); ok {
+		f.data = append([]byte(nil), data...)
+		return nil
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("unsupported message type %T", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+func (rawCodec) String() string {
+	return "toldata-raw"
+}
+
+// GRPCServer creates a gRPC server exposing the services of the gateway
+// along with the health and reflection services
+func (g *Gateway) GRPCServer(options ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append(options, grpc.CustomCodec(rawCodec{}))...)
+
+	g.health = toldata.NewHealthServer(toldata.HealthOptions{})
+	for _, service := range g.Services {
+		server.RegisterService(g.serviceDesc(service), g)
+		g.health.AddService(string(service.FullName()), serviceHealth{bus: g.Bus, service: service})
+	}
+
+	healthpb.RegisterHealthServer(server, g.health)
+	toldata.RegisterReflectionLoader(server, g.Descriptors.FileDescriptor)
+
+	return server
+}
+
+func (g *Gateway) serviceDesc(service protoreflect.ServiceDescriptor) *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: string(service.FullName()),
+		HandlerType: (*interface{})(nil),
+		Metadata:    service.ParentFile().Path(),
+	}
+
+	// Every method is served as a stream, unary calls are streams of one message.
+	// Internal methods are left out and reported as unimplemented by grpc.
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		if Internal(method) {
+			continue
+		}
+		desc.Streams = append(desc.Streams, grpc.StreamDesc{
+			StreamName:    string(method.Name()),
+			Handler:       g.grpcHandler(method),
+			ServerStreams: method.IsStreamingServer(),
+			ClientStreams: method.IsStreamingClient(),
+		})
+	}
+	return desc
+}
+
+func (g *Gateway) callContext(ctx context.Context) context.Context {
+	peerInfo := g.grpcOptions.Peer(ctx)
+	peerInfo.Gateway = g.Bus.Configuration.ID
+	return toldata.WithAuthorization(toldata.NewPeerContext(ctx, peerInfo), g.grpcOptions.Authorization(ctx))
+}
+
+func (g *Gateway) grpcHandler(method protoreflect.MethodDescriptor) grpc.StreamHandler {
+	subject := BusSubject(g.Bus, method)
+	timeout := DefaultTimeout(method)
+	event := Event(method)
+	durable := Durable(method)
+	publish := event || FireAndForget(method)
+	call := g.Bus.Call
+	if Idempotent(method) {
+		call = g.Bus.CallIdempotent
+	}
+
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		ctx := g.callContext(stream.Context())
+
+		switch {
+		case method.IsStreamingClient() && method.IsStreamingServer():
+			return status.Error(codes.Unimplemented, "bidirectional streaming is not supported")
+
+		case method.IsStreamingClient():
+			svrStream, err := g.Bus.OpenStream(ctx, subject, nil)
+			if err != nil {
+				return err
+			}
+			for {
+				req := &frame{}
+				err = stream.RecvMsg(req)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				err = svrStream.Send(req.data)
+				if err != nil {
+					return err
+				}
+			}
+
+			resp, err := svrStream.Done()
+			if err != nil {
+				return err
+			}
+			return stream.SendMsg(&frame{data: resp})
+
+		case method.IsStreamingServer():
+			req := &frame{}
+			err := stream.RecvMsg(req)
+			if err != nil {
+				return err
+			}
+			svrStream, err := g.Bus.OpenStream(ctx, subject, req.data)
+			if err != nil {
+				return err
+			}
+			for {
+				data, err := svrStream.Receive()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				err = stream.SendMsg(&frame{data: data})
+				if err != nil {
+					return err
+				}
+			}
+
+		default:
+			req := &frame{}
+			err := stream.RecvMsg(req)
+			if err != nil {
+				return err
+			}
+			if publish {
+				err = g.Bus.Publish(ctx, subject, req.data)
+				if err != nil {
+					return toldata.RequestError(subject, err)
+				}
+				return stream.SendMsg(&frame{})
+			}
+			ctx, cancel := toldata.WithDefaultTimeout(ctx, timeout)
+			defer cancel()
+			if durable {
+				err = g.Bus.PublishDurable(ctx, subject, req.data)
+				if err != nil {
+					return toldata.RequestError(subject, err)
+				}
+				return stream.SendMsg(&frame{})
+			}
+			resp, err := call(ctx, subject, req.data)
+			if err != nil {
+				return err
+			}
+			return stream.SendMsg(&frame{data: resp})
+		}
+	}
+}
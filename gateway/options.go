@@ -0,0 +1,135 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/citradigital/toldata/toldatapb"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// option returns the value of a toldata.proto option. Options decoded
+// without the extensions registered are found among the unknown fields.
+func option(options proto.Message, ext protoreflect.ExtensionType) (protoreflect.Value, bool) {
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return protoreflect.Value{}, false
+	}
+	if proto.HasExtension(options, ext) {
+		return protoreflect.ValueOf(proto.GetExtension(options, ext)), true
+	}
+
+	field := ext.TypeDescriptor()
+	raw := options.ProtoReflect().GetUnknown()
+	for len(raw) > 0 {
+		number, wireType, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			break
+		}
+		raw = raw[n:]
+
+		if number == field.Number() {
+			switch wireType {
+			case protowire.BytesType:
+				value, n := protowire.ConsumeBytes(raw)
+				return protoreflect.ValueOfString(string(value)), n >= 0
+			case protowire.VarintType:
+				value, n := protowire.ConsumeVarint(raw)
+				return protoreflect.ValueOfBool(value != 0), n >= 0
+			}
+		}
+
+		n = protowire.ConsumeFieldValue(number, wireType, raw)
+		if n < 0 {
+			break
+		}
+		raw = raw[n:]
+	}
+	return protoreflect.Value{}, false
+}
+
+func stringOption(options proto.Message, ext protoreflect.ExtensionType) string {
+	value, ok := option(options, ext)
+	if !ok {
+		return ""
+	}
+	return value.String()
+}
+
+func boolOption(options proto.Message, ext protoreflect.ExtensionType) bool {
+	value, ok := option(options, ext)
+	return ok && value.Bool()
+}
+
+// ServiceSubject returns the base of the NATS subjects of a service, as used
+// by the generated code
+func ServiceSubject(service protoreflect.ServiceDescriptor) string {
+	subject := string(service.ParentFile().Package()) + "/" + string(service.Name())
+	if prefix := stringOption(service.Options(), toldatapb.E_SubjectPrefix); prefix != "" {
+		subject = prefix + "/" + subject
+	}
+	return subject
+}
+
+// Subject returns the NATS subject of a method with the legacy subject scheme
+func Subject(method protoreflect.MethodDescriptor) string {
+	return ServiceSubject(method.Parent().(protoreflect.ServiceDescriptor)) + "/" + string(method.Name())
+}
+
+// BusSubject returns the NATS subject of a method with the subject scheme of bus
+func BusSubject(bus *toldata.Bus, method protoreflect.MethodDescriptor) string {
+	return bus.Subject(ServiceSubject(method.Parent().(protoreflect.ServiceDescriptor)), string(method.Name()))
+}
+
+// RESTMount returns the rest_mount option of a service, /api by default
+func RESTMount(service protoreflect.ServiceDescriptor) string {
+	if mount := stringOption(service.Options(), toldatapb.E_RestMount); mount != "" {
+		return mount
+	}
+	return "/api"
+}
+
+// DefaultTimeout returns the default_timeout option of a method, zero when it is not set
+func DefaultTimeout(method protoreflect.MethodDescriptor) time.Duration {
+	timeout, _ := time.ParseDuration(stringOption(method.Options(), toldatapb.E_DefaultTimeout))
+	return timeout
+}
+
+// Idempotent reports whether the calls of a method are retried while no server is available
+func Idempotent(method protoreflect.MethodDescriptor) bool {
+	return boolOption(method.Options(), toldatapb.E_Idempotent)
+}
+
+// FireAndForget reports whether a method is called without waiting for the reply
+func FireAndForget(method protoreflect.MethodDescriptor) bool {
+	return boolOption(method.Options(), toldatapb.E_FireAndForget)
+}
+
+// Internal reports whether a method is only served on the bus
+func Internal(method protoreflect.MethodDescriptor) bool {
+	return boolOption(method.Options(), toldatapb.E_Internal)
+}
+
+// Event reports whether a method publishes events, it is called without waiting for a reply
+func Event(method protoreflect.MethodDescriptor) bool {
+	return boolOption(method.Options(), toldatapb.E_Event)
+}
+
+// Durable reports whether the calls of a method are stored in a JetStream stream
+func Durable(method protoreflect.MethodDescriptor) bool {
+	return boolOption(method.Options(), toldatapb.E_Durable)
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/citradigital/toldata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// RESTRoutes describes the REST endpoints of the unary methods of the
+// exposed services, at the same paths as the generated REST gateways
+func (g *Gateway) RESTRoutes() []toldata.RESTRoute {
+	var routes []toldata.RESTRoute
+	for _, service := range g.Services {
+		mount := RESTMount(service)
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			if method.IsStreamingClient() || method.IsStreamingServer() || Internal(method) {
+				continue
+			}
+
+			path := mount + "/" + string(service.ParentFile().Package()) + "/" + string(service.Name()) + "/" + string(method.Name())
+			routes = append(routes, toldata.NewRESTRoute(string(service.FullName()), string(method.Name()), path, g.restOptions, g.restHandler(method)))
+		}
+	}
+	return routes
+}
+
+// RESTHandler returns an http.Handler serving all REST routes of the gateway
+func (g *Gateway) RESTHandler() http.Handler {
+	return toldata.RESTHandler(g.RESTRoutes())
+}
+
+func (g *Gateway) restHandler(method protoreflect.MethodDescriptor) http.HandlerFunc {
+	subject := BusSubject(g.Bus, method)
+	timeout := DefaultTimeout(method)
+	event := Event(method)
+	durable := Durable(method)
+	publish := event || FireAndForget(method)
+	call := g.Bus.Call
+	if Idempotent(method) {
+		call = g.Bus.CallIdempotent
+	}
+	types := dynamicpb.NewTypes(g.Descriptors.Files)
+	unmarshal := g.restOptions.JSON.UnmarshalOptions()
+	unmarshal.Resolver = types
+	marshal := g.restOptions.JSON.MarshalOptions()
+	marshal.Resolver = types
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		options := g.restOptions
+
+		contentType, err := options.Negotiate(r)
+		if err != nil {
+			options.WriteError(w, toldata.ContentTypeJSON, err.Error(), http.StatusNotAcceptable)
+			return
+		}
+
+		if r.Method != "POST" {
+			options.WriteError(w, contentType, "Invalid request method", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requestType, body, code, err := options.ReadBody(r)
+		if err != nil {
+			options.WriteError(w, contentType, err.Error(), code)
+			return
+		}
+
+		req := dynamicpb.NewMessage(method.Input())
+		if requestType == toldata.ContentTypeProtobuf {
+			err = proto.Unmarshal(body, req)
+		} else {
+			err = unmarshal.Unmarshal(body, req)
+		}
+		if err != nil {
+			options.WriteError(w, contentType, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reqRaw, err := proto.Marshal(req)
+		if err != nil {
+			options.WriteError(w, contentType, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		peerInfo := options.Peer(r)
+		peerInfo.Gateway = g.Bus.Configuration.ID
+		ctx := toldata.WithAuthorization(toldata.NewPeerContext(r.Context(), peerInfo), options.Authorization(r))
+		ctx, cancel := toldata.WithDefaultTimeout(ctx, timeout)
+		defer cancel()
+		var respRaw []byte
+		if publish {
+			if err = g.Bus.Publish(ctx, subject, reqRaw); err != nil {
+				err = toldata.RequestError(subject, err)
+			}
+		} else if durable {
+			if err = g.Bus.PublishDurable(ctx, subject, reqRaw); err != nil {
+				err = toldata.RequestError(subject, err)
+			}
+		} else {
+			respRaw, err = call(ctx, subject, reqRaw)
+		}
+		if err != nil {
+			options.WriteCallError(w, contentType, err)
+			return
+		}
+
+		if contentType == toldata.ContentTypeProtobuf {
+			options.WriteRawResponse(w, contentType, respRaw)
+			return
+		}
+
+		resp := dynamicpb.NewMessage(method.Output())
+		err = proto.Unmarshal(respRaw, resp)
+		if err == nil {
+			respRaw, err = marshal.Marshal(resp)
+		}
+		if err != nil {
+			options.WriteError(w, contentType, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		options.WriteRawResponse(w, contentType, respRaw)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"google.golang.org/grpc/codes"
+)
+
+// HeaderBusID carries the instance ID of the bus which sent a reply
+const HeaderBusID = "Toldata-Bus-ID"
+
+// GatherOptions bounds the replies collected by the <Method>All calls
+type GatherOptions struct {
+	// Timeout is how long the replies are collected, 1s by default. The
+	// deadline of the context is used when it comes first.
+	Timeout time.Duration
+	// Expected is how many servers are expected to reply. The call returns
+	// once all of them replied, with an error when some did not.
+	Expected int
+	// First returns the call once that many servers replied
+	First int
+}
+
+// GatherReply is the reply of a server to a gather call
+type GatherReply struct {
+	// BusID is the instance ID of the bus of the server, see Bus.InstanceID
+	BusID string
+	// Data is the encoded response when Err is nil
+	Data []byte
+	Err  error
+}
+
+// GatherSubject returns the subject on which every server of a method
+// receives the gather calls
+func GatherSubject(subject string) string {
+	return subject + "_All_"
+}
+
+// Gather sends an encoded request to every server of a method and collects
+// their replies until the bounds of options are reached
+func (bus *Bus) Gather(ctx context.Context, subject string, data []byte, options GatherOptions) ([]GatherReply, error) {
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	inbox := bus.Connection.NewRespInbox()
+	sub, err := bus.Connection.SubscribeSync(inbox)
+	if err != nil {
+		return nil, RequestError(subject, err)
+	}
+	defer sub.Unsubscribe()
+
+	msg := nats.NewMsg(GatherSubject(subject))
+	msg.Reply = inbox
+	msg.Data = data
+	if p, ok := PeerFromContext(ctx); ok {
+		setPeerHeader(msg.Header, p)
+	}
+	setVersionHeader(ctx, msg.Header)
+	setCredentialHeader(ctx, msg.Header, bus.Configuration.Credential)
+	err = bus.Connection.PublishMsg(msg)
+	if err != nil {
+		return nil, RequestError(subject, err)
+	}
+
+	var replies []GatherReply
+	for {
+		if options.First > 0 && len(replies) >= options.First {
+			return replies, nil
+		}
+		if options.Expected > 0 && len(replies) >= options.Expected {
+			return replies, nil
+		}
+
+		m, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			break
+		}
+		reply := GatherReply{BusID: m.Header.Get(HeaderBusID)}
+		reply.Data, reply.Err = decodeReply(m)
+		replies = append(replies, reply)
+	}
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return replies, RequestError(subject, ctx.Err())
+	}
+	if options.Expected > 0 {
+		return replies, &Error{
+			Message: fmt.Sprintf("%s:%d of %d servers replied", subject, len(replies), options.Expected),
+			Code:    codes.DeadlineExceeded,
+		}
+	}
+	return replies, nil
+}
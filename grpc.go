@@ -0,0 +1,57 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// GRPCOptions configures a generated gRPC gateway
+type GRPCOptions struct {
+	// TrustedProxies lists the proxies whose forwarded and x-forwarded-for
+	// metadata is used to resolve the client address
+	TrustedProxies *TrustedProxies
+}
+
+// Peer resolves the caller of a call received by the gRPC gateway
+func (o GRPCOptions) Peer(ctx context.Context) *Peer {
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	p := o.TrustedProxies.ResolvePeer(remoteAddr, md.Get("forwarded"), md.Get("x-forwarded-for"))
+	if agent := md.Get("user-agent"); len(agent) > 0 {
+		p.UserAgent = agent[0]
+	}
+	p.Protocol = "grpc"
+	return p
+}
+
+// Authorization returns the credential of a call received by the gRPC
+// gateway, the authorization metadata or the x-api-key one
+func (o GRPCOptions) Authorization(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if authorization := md.Get("authorization"); len(authorization) > 0 {
+		return authorization[0]
+	}
+	if key := md.Get("x-api-key"); len(key) > 0 {
+		return SchemeAPIKey + " " + key[0]
+	}
+	return ""
+}
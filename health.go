@@ -0,0 +1,219 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultHealthCacheDuration = 5 * time.Second
+	defaultHealthTimeout       = 2 * time.Second
+)
+
+// HealthChecker is implemented by generated clients and gRPC gateways
+type HealthChecker interface {
+	ToldataHealthCheck(ctx context.Context, req *Empty) (*ToldataHealthCheckInfo, error)
+}
+
+// GRPCGateway is implemented by the generated gRPC gateways
+type GRPCGateway interface {
+	HealthChecker
+	// ToldataServiceName returns the fully qualified name of the bridged service
+	ToldataServiceName() string
+}
+
+// HealthOptions configures the health service of a gRPC gateway
+type HealthOptions struct {
+	// CacheDuration is how long the result of a health check is reused.
+	// Defaults to 5 seconds.
+	CacheDuration time.Duration
+	// Timeout bounds each ToldataHealthCheck call. Defaults to 2 seconds.
+	Timeout time.Duration
+	// WatchInterval is how often the status of watched services is refreshed.
+	// Defaults to CacheDuration.
+	WatchInterval time.Duration
+}
+
+type healthEntry struct {
+	checker   HealthChecker
+	status    healthpb.HealthCheckResponse_ServingStatus
+	checkedAt time.Time
+}
+
+// HealthServer implements grpc.health.v1.Health for a gRPC gateway.
+// The status of each service is derived from its ToldataHealthCheck
+// method, called over NATS.
+type HealthServer struct {
+	options  HealthOptions
+	mutex    sync.Mutex
+	services map[string]*healthEntry
+	shutdown bool
+}
+
+// NewHealthServer creates a health service without any service
+func NewHealthServer(options HealthOptions) *HealthServer {
+	if options.CacheDuration <= 0 {
+		options.CacheDuration = defaultHealthCacheDuration
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultHealthTimeout
+	}
+	if options.WatchInterval <= 0 {
+		options.WatchInterval = options.CacheDuration
+	}
+
+	return &HealthServer{
+		options:  options,
+		services: make(map[string]*healthEntry),
+	}
+}
+
+// AddService registers the fully qualified service name with its health checker
+func (h *HealthServer) AddService(name string, checker HealthChecker) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.services[name] = &healthEntry{checker: checker}
+}
+
+// Shutdown reports every service as not serving, to drain the gateway
+// before it stops
+func (h *HealthServer) Shutdown() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.shutdown = true
+}
+
+// Resume undoes Shutdown
+func (h *HealthServer) Resume() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.shutdown = false
+}
+
+func (h *HealthServer) serviceStatus(ctx context.Context, name string, entry *healthEntry) healthpb.HealthCheckResponse_ServingStatus {
+	h.mutex.Lock()
+	if time.Since(entry.checkedAt) < h.options.CacheDuration {
+		defer h.mutex.Unlock()
+		return entry.status
+	}
+	h.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, h.options.Timeout)
+	defer cancel()
+
+	result := healthpb.HealthCheckResponse_SERVING
+	_, err := entry.checker.ToldataHealthCheck(ctx, &Empty{})
+	if err != nil {
+		result = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	h.mutex.Lock()
+	entry.status = result
+	entry.checkedAt = time.Now()
+	h.mutex.Unlock()
+
+	return result
+}
+
+// status returns the status of a service, the empty name stands for the
+// whole gateway which is serving when all of its services are
+func (h *HealthServer) status(ctx context.Context, name string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	h.mutex.Lock()
+	if h.shutdown {
+		_, ok := h.services[name]
+		h.mutex.Unlock()
+		return healthpb.HealthCheckResponse_NOT_SERVING, ok || name == ""
+	}
+
+	entries := make(map[string]*healthEntry)
+	for serviceName, entry := range h.services {
+		if name == "" || name == serviceName {
+			entries[serviceName] = entry
+		}
+	}
+	h.mutex.Unlock()
+
+	if name != "" && len(entries) == 0 {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+	}
+
+	result := healthpb.HealthCheckResponse_SERVING
+	for serviceName, entry := range entries {
+		if h.serviceStatus(ctx, serviceName, entry) != healthpb.HealthCheckResponse_SERVING {
+			result = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+	return result, true
+}
+
+// Check implements grpc.health.v1.Health
+func (h *HealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	result, ok := h.status(ctx, req.Service)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	return &healthpb.HealthCheckResponse{Status: result}, nil
+}
+
+// Watch implements grpc.health.v1.Health. The status is sent whenever it changes.
+func (h *HealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(h.options.WatchInterval)
+	defer ticker.Stop()
+
+	var last healthpb.HealthCheckResponse_ServingStatus = -1
+	for {
+		result, _ := h.status(ctx, req.Service)
+		if result != last {
+			err := stream.Send(&healthpb.HealthCheckResponse{Status: result})
+			if err != nil {
+				return err
+			}
+			last = result
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.Error(codes.Canceled, ctx.Err().Error())
+		case <-ticker.C:
+		}
+	}
+}
+
+// RegisterGRPCServices registers the standard health and reflection services
+// on a gRPC server hosting gateways, so load balancers and tools such as
+// grpcurl can use it. It returns the health service, e.g. to Shutdown it.
+func RegisterGRPCServices(server *grpc.Server, options HealthOptions, gateways ...GRPCGateway) *HealthServer {
+	health := NewHealthServer(options)
+	for _, gateway := range gateways {
+		health.AddService(gateway.ToldataServiceName(), gateway)
+	}
+
+	healthpb.RegisterHealthServer(server, health)
+	RegisterReflection(server)
+
+	return health
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// JSONOptions controls how messages are mapped to and from JSON by the
+// REST gateway. The zero value follows the canonical proto3 JSON mapping:
+// lowerCamelCase (or json_name) keys, int64 as strings, enums as names,
+// well-known types in their special forms and unknown fields rejected.
+type JSONOptions struct {
+	// EmitDefaults renders fields holding their zero value
+	EmitDefaults bool
+	// OrigName uses the original proto field names as keys
+	OrigName bool
+	// EnumsAsInts renders enum values as numbers instead of names
+	EnumsAsInts bool
+	// DiscardUnknown ignores unknown fields in requests instead of failing
+	DiscardUnknown bool
+	// Indent pretty prints the output using the given indentation
+	Indent string
+	// Legacy maps the messages with encoding/json, as the REST gateways did
+	// before the proto3 JSON mapping: the keys are the json tags of the Go
+	// fields, 64-bit integers and enums are numbers and unknown fields are
+	// ignored. The other options but Indent do not apply. It is not
+	// supported by the gateway command.
+	Legacy bool
+}
+
+// MarshalOptions returns the options as protojson options, used for APIv2 messages
+func (o JSONOptions) MarshalOptions() protojson.MarshalOptions {
+	return protojson.MarshalOptions{
+		UseProtoNames:   o.OrigName,
+		EmitUnpopulated: o.EmitDefaults,
+		UseEnumNumbers:  o.EnumsAsInts,
+		Indent:          o.Indent,
+	}
+}
+
+// UnmarshalOptions returns the options as protojson options, used for APIv2 messages
+func (o JSONOptions) UnmarshalOptions() protojson.UnmarshalOptions {
+	return protojson.UnmarshalOptions{
+		DiscardUnknown: o.DiscardUnknown,
+	}
+}
+
+// Marshal writes the JSON representation of msg to w
+func (o JSONOptions) Marshal(w io.Writer, msg proto.Message) error {
+	if o.Legacy {
+		var raw []byte
+		var err error
+		if o.Indent != "" {
+			raw, err = json.MarshalIndent(msg, "", o.Indent)
+		} else {
+			raw, err = json.Marshal(msg)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(raw)
+		return err
+	}
+	if v2, ok := msg.(protoreflect.ProtoMessage); ok {
+		raw, err := o.MarshalOptions().Marshal(v2)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(raw)
+		return err
+	}
+
+	m := jsonpb.Marshaler{
+		EmitDefaults: o.EmitDefaults,
+		OrigName:     o.OrigName,
+		EnumsAsInts:  o.EnumsAsInts,
+		Indent:       o.Indent,
+	}
+	return m.Marshal(w, msg)
+}
+
+// Unmarshal reads a JSON document from r into msg
+func (o JSONOptions) Unmarshal(r io.Reader, msg proto.Message) error {
+	if o.Legacy {
+		return json.NewDecoder(r).Decode(msg)
+	}
+	if v2, ok := msg.(protoreflect.ProtoMessage); ok {
+		raw, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return o.UnmarshalOptions().Unmarshal(raw, v2)
+	}
+
+	u := jsonpb.Unmarshaler{
+		AllowUnknownFields: o.DiscardUnknown,
+	}
+	return u.Unmarshal(r, msg)
+}
+
+// WriteError writes an ErrorMessage as the JSON body of an HTTP error response
+func (o JSONOptions) WriteError(w http.ResponseWriter, message string, code int) {
+	buf := bytes.NewBuffer(nil)
+	err := o.Marshal(buf, &ErrorMessage{
+		ErrorMessage: message,
+		Timestamp:    time.Now().Unix(),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, "{\"error-message\": \"internal-server-error\"}")
+		return
+	}
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
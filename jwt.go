@@ -0,0 +1,316 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// KeySet holds the keys verifying the JWTs by key ID: []byte for HS256,
+// HS384 and HS512, *rsa.PublicKey for RS256 to PS512, *ecdsa.PublicKey for
+// ES256 to ES512 and ed25519.PublicKey for EdDSA
+type KeySet map[string]interface{}
+
+// jwk is a key of a JWKS document, RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS reads the keys of a JWKS document. The keys which are not used
+// for signatures are left out.
+func ParseJWKS(data []byte) (KeySet, error) {
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	err := json.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(KeySet)
+	for _, k := range document.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// LoadJWKS reads the keys of a JWKS file
+func LoadJWKS(path string) (KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "oct":
+		return decode(k.K)
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// JWTVerifier verifies the bearer tokens, JWTs signed with one of its keys
+type JWTVerifier struct {
+	Keys KeySet
+	// Issuer is the iss claim required when set
+	Issuer string
+	// Audience is required in the aud claim when set
+	Audience string
+	// Leeway is the clock skew tolerated on exp and nbf
+	Leeway time.Duration
+	// Now returns the current time, time.Now by default
+	Now func() time.Time
+}
+
+// Verify implements Verifier
+func (v *JWTVerifier) Verify(ctx context.Context, credential Credential) (*Principal, error) {
+	if credential.Scheme != SchemeBearer {
+		return nil, ErrUnsupportedCredential
+	}
+	claims, err := v.verifyToken(credential.Value)
+	if err != nil {
+		return nil, err
+	}
+	subject, _ := claims["sub"].(string)
+	return &Principal{Subject: subject, Scheme: SchemeBearer, Claims: claims}, nil
+}
+
+func (v *JWTVerifier) verifyToken(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	// A token without key ID is tried with every key
+	keys := v.Keys
+	if header.Kid != "" {
+		key, ok := v.Keys[header.Kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", header.Kid)
+		}
+		keys = KeySet{header.Kid: key}
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		err = verifySignature(header.Alg, key, signed, signature)
+		if err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		if err == nil {
+			err = errors.New("no key")
+		}
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, err
+	}
+	return claims, v.validate(claims)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	return json.Unmarshal(data, v)
+}
+
+var jwtHashes = map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+
+// jwtCurves are the curves of the ES algorithms, RFC 7518
+var jwtCurves = map[string]string{"ES256": "P-256", "ES384": "P-384", "ES512": "P-521"}
+
+// verifySignature checks the signature of a JWT, the algorithm must match
+// the type of the key, and the curve of the ES algorithms
+func verifySignature(alg string, key interface{}, signed, signature []byte) error {
+	invalid := errors.New("invalid signature")
+	if alg == "EdDSA" {
+		k, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(k, signed, signature) {
+			return invalid
+		}
+		return nil
+	}
+
+	hash, ok := jwtHashes[strings.TrimLeft(alg, "HRPES")]
+	if !ok || len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case []byte:
+		if alg[:2] != "HS" {
+			return invalid
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return invalid
+		}
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			err := rsa.VerifyPKCS1v15(k, hash, digest, signature)
+			if err != nil {
+				return invalid
+			}
+		case "PS":
+			err := rsa.VerifyPSS(k, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+			if err != nil {
+				return invalid
+			}
+		default:
+			return invalid
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if jwtCurves[alg] != k.Curve.Params().Name || len(signature) != 2*size {
+			return invalid
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return invalid
+		}
+	default:
+		return invalid
+	}
+	return nil
+}
+
+// validate checks the time, issuer and audience claims
+func (v *JWTVerifier) validate(claims map[string]interface{}) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(v.Leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+	if v.Issuer != "" && claims["iss"] != v.Issuer {
+		return errors.New("invalid issuer")
+	}
+	if v.Audience != "" {
+		found := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == v.Audience
+		case []interface{}:
+			for _, a := range aud {
+				found = found || a == v.Audience
+			}
+		}
+		if !found {
+			return errors.New("invalid audience")
+		}
+	}
+	return nil
+}
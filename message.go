@@ -0,0 +1,37 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"github.com/gogo/protobuf/proto"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Messages generated by gogo and by protoc-gen-go (APIv2) are both accepted,
+// the latter are recognized by their ProtoReflect method.
+
+func marshalMessage(msg proto.Message) ([]byte, error) {
+	if m, ok := msg.(protoreflect.ProtoMessage); ok {
+		return protov2.Marshal(m)
+	}
+	return proto.Marshal(msg)
+}
+
+func unmarshalMessage(data []byte, msg proto.Message) error {
+	if m, ok := msg.(protoreflect.ProtoMessage); ok {
+		return protov2.Unmarshal(data, m)
+	}
+	return proto.Unmarshal(data, msg)
+}
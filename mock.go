@@ -0,0 +1,118 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+)
+
+// ErrNotScripted is returned by generated mocks called without a scripted result
+var ErrNotScripted = &Error{Message: "not-scripted", Code: codes.Unimplemented}
+
+// MockBusID is the BusID of the replies of the gather calls of generated mocks
+const MockBusID = "mock"
+
+// MockCall is a call recorded by a generated mock
+type MockCall struct {
+	// Method is the name of the called method
+	Method string
+	// Request is the request of unary and server streaming calls
+	Request interface{}
+	// Sent are the messages received on client streams, in order
+	Sent []interface{}
+}
+
+// Mock records the calls made to a generated mock and holds the results
+// scripted for them. It is safe for concurrent use.
+type Mock struct {
+	mu      sync.Mutex
+	calls   []*MockCall
+	results map[string][]interface{}
+	last    map[string]interface{}
+}
+
+// Record adds a call to the history
+func (m *Mock) Record(method string, request interface{}) *MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	call := &MockCall{Method: method, Request: request}
+	m.calls = append(m.calls, call)
+	return call
+}
+
+// RecordSent adds a message received on the stream of a recorded call
+func (m *Mock) RecordSent(call *MockCall, message interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	call.Sent = append(call.Sent, message)
+}
+
+// Calls returns the recorded calls in order
+func (m *Mock) Calls() []MockCall {
+	return m.CallsTo("")
+}
+
+// CallsTo returns the recorded calls to a method, all calls when method is empty
+func (m *Mock) CallsTo(method string) []MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var calls []MockCall
+	for _, call := range m.calls {
+		if method == "" || call.Method == method {
+			c := *call
+			c.Sent = append([]interface{}(nil), call.Sent...)
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Script appends a result for the calls to method
+func (m *Mock) Script(method string, result interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.results == nil {
+		m.results = make(map[string][]interface{})
+	}
+	m.results[method] = append(m.results[method], result)
+}
+
+// Next returns the next scripted result of method. Results are used in
+// order, the last one is used again once all were used.
+func (m *Mock) Next(method string) (interface{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	results := m.results[method]
+	if len(results) == 0 {
+		result, ok := m.last[method]
+		return result, ok
+	}
+	if m.last == nil {
+		m.last = make(map[string]interface{})
+	}
+	m.results[method] = results[1:]
+	m.last[method] = results[0]
+	return results[0], true
+}
+
+// Reset forgets the recorded calls and the scripted results
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+	m.results = nil
+	m.last = nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// Headers carrying the caller information across the bus
+const (
+	HeaderPeerAddress = "Toldata-Peer-Address"
+	HeaderUserAgent   = "Toldata-User-Agent"
+	HeaderGateway     = "Toldata-Gateway"
+	HeaderProtocol    = "Toldata-Protocol"
+)
+
+// Peer describes the original caller of a method relayed by a gateway
+type Peer struct {
+	// Addr is the resolved address of the client
+	Addr net.Addr
+	// UserAgent is the user agent reported by the client
+	UserAgent string
+	// Gateway is the bus ID of the gateway which relayed the call
+	Gateway string
+	// Protocol is the protocol spoken by the client to the gateway, e.g. http or grpc
+	Protocol string
+}
+
+type peerKey struct{}
+
+// NewPeerContext returns a context carrying the peer. Calls made with the
+// context send the peer along so the server can retrieve it with PeerFromContext.
+func NewPeerContext(ctx context.Context, p *Peer) context.Context {
+	return context.WithValue(ctx, peerKey{}, p)
+}
+
+// PeerFromContext returns the original caller of the current call
+func PeerFromContext(ctx context.Context) (*Peer, bool) {
+	p, ok := ctx.Value(peerKey{}).(*Peer)
+	return p, ok
+}
+
+// TrustedProxies resolves the client address of requests relayed by
+// reverse proxies. Forwarding headers are only honoured when they are
+// set by one of the trusted proxies.
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies creates a TrustedProxies from a list of IP addresses or CIDR ranges
+func NewTrustedProxies(proxies ...string) (*TrustedProxies, error) {
+	t := &TrustedProxies{}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy = proxy + "/128"
+			} else {
+				proxy = proxy + "/32"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		t.networks = append(t.networks, network)
+	}
+	return t, nil
+}
+
+func (t *TrustedProxies) isTrusted(ip net.IP) bool {
+	if t == nil || ip == nil {
+		return false
+	}
+	for _, network := range t.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP resolves the client address from the address of the direct
+// connection and the chain of forwarded addresses, listed from the
+// original client to the last proxy. The chain is walked from the right
+// and the first address not belonging to a trusted proxy is returned.
+func (t *TrustedProxies) ClientIP(remote net.IP, forwarded []string) net.IP {
+	if !t.isTrusted(remote) {
+		return remote
+	}
+
+	client := remote
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := parseHost(forwarded[i])
+		if ip == nil {
+			break
+		}
+		client = ip
+		if !t.isTrusted(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// ForwardedFor extracts the forwarded addresses from a Forwarded (RFC 7239)
+// header, falling back to X-Forwarded-For when it is absent
+func ForwardedFor(forwarded, xForwardedFor []string) []string {
+	var result []string
+
+	for _, header := range forwarded {
+		for _, element := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+					result = append(result, strings.Trim(kv[1], "\""))
+				}
+			}
+		}
+	}
+	if len(result) > 0 {
+		return result
+	}
+
+	for _, header := range xForwardedFor {
+		for _, item := range strings.Split(header, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
+
+// parseHost parses an address with an optional port and IPv6 brackets
+func parseHost(address string) net.IP {
+	address = strings.TrimSpace(address)
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	address = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+	if i := strings.Index(address, "%"); i != -1 {
+		address = address[:i]
+	}
+	return net.ParseIP(address)
+}
+
+// ResolvePeer builds the peer of a call received by a gateway from the
+// address of the direct connection and the forwarding headers
+func (t *TrustedProxies) ResolvePeer(remoteAddr string, forwarded, xForwardedFor []string) *Peer {
+	ip := t.ClientIP(parseHost(remoteAddr), ForwardedFor(forwarded, xForwardedFor))
+	if ip == nil {
+		return &Peer{Addr: &net.IPAddr{}}
+	}
+	return &Peer{Addr: &net.IPAddr{IP: ip}}
+}
+
+func setPeerHeader(header nats.Header, p *Peer) {
+	if p.Addr != nil {
+		header.Set(HeaderPeerAddress, p.Addr.String())
+	}
+	if p.UserAgent != "" {
+		header.Set(HeaderUserAgent, p.UserAgent)
+	}
+	if p.Gateway != "" {
+		header.Set(HeaderGateway, p.Gateway)
+	}
+	if p.Protocol != "" {
+		header.Set(HeaderProtocol, p.Protocol)
+	}
+}
+
+func peerFromHeader(header nats.Header) (*Peer, bool) {
+	address := header.Get(HeaderPeerAddress)
+	if address == "" {
+		return nil, false
+	}
+
+	return &Peer{
+		Addr:      &net.IPAddr{IP: parseHost(address)},
+		UserAgent: header.Get(HeaderUserAgent),
+		Gateway:   header.Get(HeaderGateway),
+		Protocol:  header.Get(HeaderProtocol),
+	}, true
+}
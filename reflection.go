@@ -0,0 +1,324 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"sync"
+
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	golangproto "github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// reflectionServer implements grpc.reflection.v1alpha.ServerReflection with
+// the file descriptors embedded in the generated code. Unlike the stock
+// implementation it looks up the gogo registry, where the toldata messages
+// are registered.
+type reflectionServer struct {
+	server *grpc.Server
+	loader FileDescriptorLoader
+
+	once       sync.Once
+	files      map[string][]byte
+	symbols    map[string]string
+	extensions map[string]map[int32]string
+}
+
+// FileDescriptorLoader returns the serialized FileDescriptorProto of a proto
+// file, or nil when the file is unknown
+type FileDescriptorLoader func(name string) []byte
+
+// RegisterReflection registers the server reflection service on a gRPC
+// server. The services registered on the server are indexed on the first
+// reflection request.
+func RegisterReflection(server *grpc.Server) {
+	RegisterReflectionLoader(server, nil)
+}
+
+// RegisterReflectionLoader registers the server reflection service with
+// descriptors which are not compiled in, e.g. loaded from a descriptor set.
+// Files unknown to the loader are looked up in the registries.
+func RegisterReflectionLoader(server *grpc.Server, loader FileDescriptorLoader) {
+	rpb.RegisterServerReflectionServer(server, &reflectionServer{server: server, loader: loader})
+}
+
+func (s *reflectionServer) load(name string) (*descriptor.FileDescriptorProto, []byte) {
+	if s.loader != nil {
+		if data := s.loader(name); data != nil {
+			fd := &descriptor.FileDescriptorProto{}
+			if err := gogoproto.Unmarshal(data, fd); err == nil {
+				return fd, data
+			}
+		}
+	}
+	return loadFileDescriptor(name)
+}
+
+// loadFileDescriptor returns the serialized FileDescriptorProto of a file
+// registered either by gogo or golang protobuf generated code
+func loadFileDescriptor(name string) (*descriptor.FileDescriptorProto, []byte) {
+	gz := gogoproto.FileDescriptor(name)
+	if gz == nil {
+		gz = golangproto.FileDescriptor(name)
+	}
+	if gz == nil {
+		// Files compiled with a different include path are registered
+		// under their base name only
+		gz = gogoproto.FileDescriptor(path.Base(name))
+	}
+	if gz == nil {
+		return nil, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, nil
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, nil
+	}
+
+	fd := &descriptor.FileDescriptorProto{}
+	err = gogoproto.Unmarshal(data, fd)
+	if err != nil {
+		return nil, nil
+	}
+
+	if fd.GetName() != name {
+		// Report the file under the name its dependents import it with
+		fd.Name = &name
+		data, err = gogoproto.Marshal(fd)
+		if err != nil {
+			return nil, nil
+		}
+	}
+	return fd, data
+}
+
+func (s *reflectionServer) index() {
+	s.files = make(map[string][]byte)
+	s.symbols = make(map[string]string)
+	s.extensions = make(map[string]map[int32]string)
+
+	for _, info := range s.server.GetServiceInfo() {
+		if name, ok := info.Metadata.(string); ok {
+			s.addFile(name)
+		}
+	}
+}
+
+func (s *reflectionServer) addFile(name string) {
+	if _, ok := s.files[name]; ok {
+		return
+	}
+
+	fd, data := s.load(name)
+	if fd == nil {
+		return
+	}
+	s.files[name] = data
+
+	prefix := ""
+	if fd.GetPackage() != "" {
+		prefix = fd.GetPackage() + "."
+	}
+
+	for _, svc := range fd.Service {
+		serviceName := prefix + svc.GetName()
+		s.symbols[serviceName] = name
+		for _, method := range svc.Method {
+			s.symbols[serviceName+"."+method.GetName()] = name
+		}
+	}
+	for _, msg := range fd.MessageType {
+		s.addMessage(name, prefix, msg)
+	}
+	for _, enum := range fd.EnumType {
+		s.symbols[prefix+enum.GetName()] = name
+	}
+	s.addExtensions(name, prefix, fd.Extension)
+
+	for _, dependency := range fd.Dependency {
+		s.addFile(dependency)
+	}
+}
+
+func (s *reflectionServer) addMessage(file, prefix string, msg *descriptor.DescriptorProto) {
+	messageName := prefix + msg.GetName()
+	s.symbols[messageName] = file
+
+	for _, nested := range msg.NestedType {
+		s.addMessage(file, messageName+".", nested)
+	}
+	for _, enum := range msg.EnumType {
+		s.symbols[messageName+"."+enum.GetName()] = file
+	}
+	s.addExtensions(file, messageName+".", msg.Extension)
+}
+
+func (s *reflectionServer) addExtensions(file, prefix string, extensions []*descriptor.FieldDescriptorProto) {
+	for _, ext := range extensions {
+		s.symbols[prefix+ext.GetName()] = file
+
+		extendee := ext.GetExtendee()
+		if len(extendee) > 0 && extendee[0] == '.' {
+			extendee = extendee[1:]
+		}
+		if s.extensions[extendee] == nil {
+			s.extensions[extendee] = make(map[int32]string)
+		}
+		s.extensions[extendee][ext.GetNumber()] = file
+	}
+}
+
+// fileResponse returns the file with its transitive dependencies
+func (s *reflectionServer) fileResponse(name string) *rpb.FileDescriptorResponse {
+	result := &rpb.FileDescriptorResponse{}
+	seen := make(map[string]bool)
+
+	var walk func(name string)
+	walk = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+
+		fd, data := s.load(name)
+		if fd == nil {
+			return
+		}
+		result.FileDescriptorProto = append(result.FileDescriptorProto, data)
+		for _, dependency := range fd.Dependency {
+			walk(dependency)
+		}
+	}
+	walk(name)
+
+	return result
+}
+
+func reflectionError(code codes.Code, message string) *rpb.ServerReflectionResponse_ErrorResponse {
+	return &rpb.ServerReflectionResponse_ErrorResponse{
+		ErrorResponse: &rpb.ErrorResponse{
+			ErrorCode:    int32(code),
+			ErrorMessage: message,
+		},
+	}
+}
+
+func (s *reflectionServer) respond(req *rpb.ServerReflectionRequest) *rpb.ServerReflectionResponse {
+	resp := &rpb.ServerReflectionResponse{
+		ValidHost:       req.Host,
+		OriginalRequest: req,
+	}
+
+	switch r := req.MessageRequest.(type) {
+	case *rpb.ServerReflectionRequest_FileByFilename:
+		if fd, _ := s.load(r.FileByFilename); fd == nil {
+			resp.MessageResponse = reflectionError(codes.NotFound, "file not found: "+r.FileByFilename)
+			break
+		}
+		resp.MessageResponse = &rpb.ServerReflectionResponse_FileDescriptorResponse{
+			FileDescriptorResponse: s.fileResponse(r.FileByFilename),
+		}
+
+	case *rpb.ServerReflectionRequest_FileContainingSymbol:
+		file, ok := s.symbols[r.FileContainingSymbol]
+		if !ok {
+			resp.MessageResponse = reflectionError(codes.NotFound, "symbol not found: "+r.FileContainingSymbol)
+			break
+		}
+		resp.MessageResponse = &rpb.ServerReflectionResponse_FileDescriptorResponse{
+			FileDescriptorResponse: s.fileResponse(file),
+		}
+
+	case *rpb.ServerReflectionRequest_FileContainingExtension:
+		file, ok := s.extensions[r.FileContainingExtension.ContainingType][r.FileContainingExtension.ExtensionNumber]
+		if !ok {
+			resp.MessageResponse = reflectionError(codes.NotFound, "extension not found")
+			break
+		}
+		resp.MessageResponse = &rpb.ServerReflectionResponse_FileDescriptorResponse{
+			FileDescriptorResponse: s.fileResponse(file),
+		}
+
+	case *rpb.ServerReflectionRequest_AllExtensionNumbersOfType:
+		if _, ok := s.symbols[r.AllExtensionNumbersOfType]; !ok {
+			resp.MessageResponse = reflectionError(codes.NotFound, "type not found: "+r.AllExtensionNumbersOfType)
+			break
+		}
+		numbers := []int32{}
+		for number := range s.extensions[r.AllExtensionNumbersOfType] {
+			numbers = append(numbers, number)
+		}
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+		resp.MessageResponse = &rpb.ServerReflectionResponse_AllExtensionNumbersResponse{
+			AllExtensionNumbersResponse: &rpb.ExtensionNumberResponse{
+				BaseTypeName:    r.AllExtensionNumbersOfType,
+				ExtensionNumber: numbers,
+			},
+		}
+
+	case *rpb.ServerReflectionRequest_ListServices:
+		names := []string{}
+		for name := range s.server.GetServiceInfo() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		list := &rpb.ListServiceResponse{}
+		for _, name := range names {
+			list.Service = append(list.Service, &rpb.ServiceResponse{Name: name})
+		}
+		resp.MessageResponse = &rpb.ServerReflectionResponse_ListServicesResponse{
+			ListServicesResponse: list,
+		}
+
+	default:
+		resp.MessageResponse = reflectionError(codes.InvalidArgument, "invalid message request")
+	}
+
+	return resp
+}
+
+// ServerReflectionInfo implements grpc.reflection.v1alpha.ServerReflection
+func (s *reflectionServer) ServerReflectionInfo(stream rpb.ServerReflection_ServerReflectionInfoServer) error {
+	s.once.Do(s.index)
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = stream.Send(s.respond(req))
+		if err != nil {
+			return err
+		}
+	}
+}
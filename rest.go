@@ -0,0 +1,412 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+
+	// DefaultMaxBodySize is the request body limit used when RESTOptions.MaxBodySize is zero
+	DefaultMaxBodySize = 4 << 20
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported-media-type")
+	ErrNotAcceptable        = errors.New("not-acceptable")
+	ErrBodyTooLarge         = errors.New("request-body-too-large")
+)
+
+// RESTOptions configures a generated REST gateway
+type RESTOptions struct {
+	// JSON controls the proto3 JSON mapping of requests and responses
+	JSON JSONOptions
+	// MaxBodySize limits the size of request bodies in bytes.
+	// Zero means DefaultMaxBodySize, a negative value disables the limit.
+	MaxBodySize int64
+	// AllowedOrigins lists the origins allowed to make cross-origin requests,
+	// "*" allows any origin. CORS is disabled when empty.
+	AllowedOrigins []string
+	// AllowedHeaders lists the request headers allowed in cross-origin requests
+	// in addition to Content-Type and Accept
+	AllowedHeaders []string
+	// AllowCredentials allows cross-origin requests to carry credentials
+	AllowCredentials bool
+	// CORSMaxAge is how long browsers may cache a preflight response
+	CORSMaxAge time.Duration
+	// Gzip compresses responses for clients accepting gzip encoding
+	Gzip bool
+	// TrustedProxies lists the proxies whose Forwarded and X-Forwarded-For
+	// headers are used to resolve the client address
+	TrustedProxies *TrustedProxies
+}
+
+// RESTRoute describes an endpoint of a REST gateway and the toldata method it calls
+type RESTRoute struct {
+	// Service is the fully qualified proto service name, e.g. cdl.toldatatest.TestService
+	Service string
+	// Method is the name of the RPC method
+	Method string
+	// HTTPMethod is the HTTP verb the route answers to
+	HTTPMethod string
+	// Path is the URL path of the route
+	Path    string
+	Handler http.Handler
+}
+
+// FullMethod returns the method name in the gRPC form /package.Service/Method
+func (route RESTRoute) FullMethod() string {
+	return "/" + route.Service + "/" + route.Method
+}
+
+type restRouteKey struct{}
+
+// NewRESTRoute creates a route whose handler is wrapped with the middleware of the options
+// and makes the route available to the handler through RESTRouteFromContext
+func NewRESTRoute(service, method, path string, options RESTOptions, handler http.HandlerFunc) RESTRoute {
+	route := RESTRoute{
+		Service:    service,
+		Method:     method,
+		HTTPMethod: http.MethodPost,
+		Path:       path,
+	}
+
+	route.Handler = options.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), restRouteKey{}, route)
+		handler(w, r.WithContext(ctx))
+	}))
+
+	return route
+}
+
+// RESTRouteFromContext returns the route serving the current request
+func RESTRouteFromContext(ctx context.Context) (RESTRoute, bool) {
+	route, ok := ctx.Value(restRouteKey{}).(RESTRoute)
+	return route, ok
+}
+
+// RESTHandler serves a set of routes on a dedicated http.ServeMux
+func RESTHandler(routes []RESTRoute) http.Handler {
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		mux.Handle(route.Path, route.Handler)
+	}
+	return mux
+}
+
+func (o RESTOptions) maxBodySize() int64 {
+	if o.MaxBodySize == 0 {
+		return DefaultMaxBodySize
+	}
+	return o.MaxBodySize
+}
+
+func (o RESTOptions) isOriginAllowed(origin string) bool {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware applies CORS and response compression to a gateway handler
+func (o RESTOptions) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && len(o.AllowedOrigins) > 0 {
+			w.Header().Add("Vary", "Origin")
+
+			if o.isOriginAllowed(origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if o.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+
+				if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+					headers := append([]string{"Content-Type", "Accept"}, o.AllowedHeaders...)
+					w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+					w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+					if o.CORSMaxAge > 0 {
+						w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(o.CORSMaxAge.Seconds())))
+					}
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+		}
+
+		if o.Gzip && acceptsGzip(r) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			gz := &gzipResponseWriter{ResponseWriter: w}
+			defer gz.Close()
+			w = gz
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Peer resolves the caller of a request received by the REST gateway
+func (o RESTOptions) Peer(r *http.Request) *Peer {
+	p := o.TrustedProxies.ResolvePeer(r.RemoteAddr, r.Header.Values("Forwarded"), r.Header.Values("X-Forwarded-For"))
+	p.UserAgent = r.UserAgent()
+	p.Protocol = "http"
+	return p
+}
+
+// Authorization returns the credential of a request received by the REST
+// gateway, the Authorization header or the X-Api-Key one
+func (o RESTOptions) Authorization(r *http.Request) string {
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		return authorization
+	}
+	if key := r.Header.Get("X-Api-Key"); key != "" {
+		return SchemeAPIKey + " " + key
+	}
+	return ""
+}
+
+// Negotiate picks the response content type from the Accept header of the request.
+// Without an Accept header the response mirrors the request content type.
+func (o RESTOptions) Negotiate(r *http.Request) (string, error) {
+	preferred := ContentTypeJSON
+	if mediaType(r.Header.Get("Content-Type")) == ContentTypeProtobuf {
+		preferred = ContentTypeProtobuf
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return preferred, nil
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, item := range strings.Split(accept, ",") {
+		media, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		candidate := ""
+		switch media {
+		case ContentTypeJSON, ContentTypeProtobuf:
+			candidate = media
+		case "*/*", "application/*":
+			candidate = preferred
+		}
+
+		if candidate != "" && q > bestQ {
+			best = candidate
+			bestQ = q
+		}
+	}
+
+	if best == "" {
+		return "", ErrNotAcceptable
+	}
+	return best, nil
+}
+
+// ReadBody reads the body of the request, enforcing the body size limit, and
+// returns it with its media type. The returned status code describes the
+// failure when err is not nil.
+func (o RESTOptions) ReadBody(r *http.Request) (string, []byte, int, error) {
+	contentType := mediaType(r.Header.Get("Content-Type"))
+	if contentType != "" && contentType != ContentTypeJSON && contentType != ContentTypeProtobuf {
+		return "", nil, http.StatusUnsupportedMediaType, ErrUnsupportedMediaType
+	}
+
+	var body io.Reader = r.Body
+	limit := o.maxBodySize()
+	if limit > 0 {
+		body = io.LimitReader(r.Body, limit+1)
+	}
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", nil, http.StatusBadRequest, err
+	}
+	if limit > 0 && int64(len(raw)) > limit {
+		return "", nil, http.StatusRequestEntityTooLarge, ErrBodyTooLarge
+	}
+
+	return contentType, raw, http.StatusOK, nil
+}
+
+// ReadRequest decodes the body of the request into msg according to its content type.
+// The returned status code describes the failure when err is not nil.
+func (o RESTOptions) ReadRequest(r *http.Request, msg proto.Message) (int, error) {
+	contentType, raw, code, err := o.ReadBody(r)
+	if err != nil {
+		return code, err
+	}
+
+	if contentType == ContentTypeProtobuf {
+		err = unmarshalMessage(raw, msg)
+	} else {
+		err = o.JSON.Unmarshal(bytes.NewReader(raw), msg)
+	}
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// WriteResponse encodes msg into the response using the negotiated content type
+func (o RESTOptions) WriteResponse(w http.ResponseWriter, contentType string, msg proto.Message) {
+	var raw []byte
+	var err error
+
+	if contentType == ContentTypeProtobuf {
+		raw, err = marshalMessage(msg)
+	} else {
+		buf := bytes.NewBuffer(nil)
+		err = o.JSON.Marshal(buf, msg)
+		raw = buf.Bytes()
+	}
+
+	if err != nil {
+		o.WriteError(w, contentType, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	o.WriteRawResponse(w, contentType, raw)
+}
+
+// WriteRawResponse writes a response already encoded with the negotiated content type
+func (o RESTOptions) WriteRawResponse(w http.ResponseWriter, contentType string, raw []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Write(raw)
+}
+
+// WriteCallError writes the error of a call made on the bus, methods which
+// are not implemented are reported as 501 Not Implemented
+func (o RESTOptions) WriteCallError(w http.ResponseWriter, contentType string, err error) {
+	code := http.StatusInternalServerError
+	switch status.Code(err) {
+	case codes.Unimplemented:
+		code = http.StatusNotImplemented
+	case codes.Unauthenticated:
+		code = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	}
+	o.WriteError(w, contentType, err.Error(), code)
+}
+
+// WriteError writes an ErrorMessage using the negotiated content type
+func (o RESTOptions) WriteError(w http.ResponseWriter, contentType, message string, code int) {
+	if contentType != ContentTypeProtobuf {
+		o.JSON.WriteError(w, message, code)
+		return
+	}
+
+	raw, err := proto.Marshal(&ErrorMessage{
+		ErrorMessage: message,
+		Timestamp:    time.Now().Unix(),
+	})
+	if err != nil {
+		o.JSON.WriteError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProtobuf)
+	w.WriteHeader(code)
+	w.Write(raw)
+}
+
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return media
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, item := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(item, ";")
+		if strings.TrimSpace(parts[0]) != "gzip" {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if q := strings.Replace(strings.TrimSpace(param), " ", "", -1); q == "q=0" || q == "q=0.0" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	writer *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.writer == nil {
+		g.start()
+	}
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipResponseWriter) Write(data []byte) (int, error) {
+	if g.writer == nil {
+		g.start()
+	}
+	return g.writer.Write(data)
+}
+
+func (g *gzipResponseWriter) start() {
+	g.Header().Del("Content-Length")
+	g.Header().Set("Content-Encoding", "gzip")
+	g.writer = gzip.NewWriter(g.ResponseWriter)
+}
+
+func (g *gzipResponseWriter) Close() {
+	if g.writer != nil {
+		g.writer.Close()
+	}
+}
@@ -5,4 +5,6 @@ su -l nobody
 cd /src/test
 env
 echo "Starting test...."
-go test -test.parallel 4
+# test and test/golang declare the same services on the NATS server of the
+# environment, run the packages one at a time
+go test -p 1 -test.parallel 4 ./...
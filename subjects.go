@@ -0,0 +1,113 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"strings"
+)
+
+// SubjectScheme builds the NATS subjects of the methods from the subject of
+// their service, [<subject_prefix>/]<package>/<Service>. The zero value is
+// the legacy scheme, <subject_prefix>/<package>/<Service>/<Method>.
+type SubjectScheme struct {
+	// Prefix is the first segment of the subjects
+	Prefix string
+	// Environment is the segment following the prefix, e.g. staging
+	Environment string
+	// Separator joins the segments, / by default. With . the subjects can
+	// be matched by the NATS wildcards and permissions.
+	Separator string
+	// Format builds the subject of a method instead when set. The suffixes of
+	// the stream, gather and version subjects are appended to it.
+	Format func(service, method string) string
+}
+
+func (s SubjectScheme) separator() string {
+	if s.Separator == "" {
+		return "/"
+	}
+	return s.Separator
+}
+
+// segments returns the prefix and the environment followed by the segments
+func (s SubjectScheme) segments(segments ...string) []string {
+	var result []string
+	for _, segment := range []string{s.Prefix, s.Environment} {
+		if segment != "" {
+			result = append(result, segment)
+		}
+	}
+	return append(result, segments...)
+}
+
+// Subject returns the subject of a method of the service
+func (s SubjectScheme) Subject(service, method string) string {
+	if s.Format != nil {
+		return s.Format(service, method)
+	}
+	segments := s.segments(strings.Split(service, "/")...)
+	return strings.Join(append(segments, method), s.separator())
+}
+
+// QueueGroup returns the queue group of the servers, prefixed with the
+// prefix and the environment
+func (s SubjectScheme) QueueGroup(group string) string {
+	return strings.Join(s.segments(group), s.separator())
+}
+
+// StreamName returns the name of the durable stream of the service
+func (s SubjectScheme) StreamName(service string) string {
+	return natsName(strings.Join(s.segments(service), "_"))
+}
+
+// Subject returns the subject of a method with the scheme of the bus
+func (bus *Bus) Subject(service, method string) string {
+	return bus.Configuration.Subjects.Subject(service, method)
+}
+
+// QueueGroup returns the queue group of the servers with the scheme of the bus
+func (bus *Bus) QueueGroup(group string) string {
+	return bus.Configuration.Subjects.QueueGroup(group)
+}
+
+// ServerSubject is a subject on which the servers receive the calls of a
+// method, with the queue group and the durable stream of its scheme
+type ServerSubject struct {
+	Subject    string
+	QueueGroup string
+	Stream     string
+}
+
+// ServerSubjects returns the subjects on which the servers receive the
+// calls of a method, with the scheme of the bus and the one it migrates from.
+// The subjects of the previous scheme keep its queue group, so the servers
+// not migrated yet are in the same group.
+func (bus *Bus) ServerSubjects(service, method, group string) []ServerSubject {
+	current := bus.Configuration.Subjects
+	subjects := []ServerSubject{{
+		Subject:    current.Subject(service, method),
+		QueueGroup: current.QueueGroup(group),
+		Stream:     current.StreamName(service),
+	}}
+	if previous := bus.Configuration.PreviousSubjects; previous != nil {
+		if subject := previous.Subject(service, method); subject != subjects[0].Subject {
+			subjects = append(subjects, ServerSubject{
+				Subject:    subject,
+				QueueGroup: previous.QueueGroup(group),
+				Stream:     previous.StreamName(service),
+			})
+		}
+	}
+	return subjects
+}
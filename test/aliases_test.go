@@ -0,0 +1,118 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/stretchr/testify/assert"
+)
+
+type renamedService struct{}
+
+func (s *renamedService) ToldataHealthCheck(ctx context.Context, req *Empty) (*ToldataHealthCheckInfo, error) {
+	return &ToldataHealthCheckInfo{Data: "renamed"}, nil
+}
+
+func (s *renamedService) Echo(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	return &TestAResponse{Output: req.Input}, nil
+}
+
+func (s *renamedService) Count(req *StreamDataRequest, stream RenamedService_CountToldataServer) error {
+	return stream.Send(&StreamDataResponse{Data: req.Id})
+}
+
+func TestAliases(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+
+	var mutex sync.Mutex
+	observed := make(map[string]int)
+
+	server := NewRenamedServiceToldataServer(bus, &renamedService{})
+	server.Aliases = []string{"cdl.older/FirstService"}
+	server.Discovery = &toldata.DiscoveryOptions{}
+	server.OnAlias = func(alias, method string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		observed[alias+" "+method]++
+	}
+	_, err = server.SubscribeRenamedService()
+	assert.Equal(t, nil, err)
+	bus.Connection.Flush()
+
+	t.Run("Subject", func(t *testing.T) {
+		assert.Equal(t, "cdl.legacy/OriginalService", toldata.AliasSubject("", "cdl.legacy.OriginalService"))
+		assert.Equal(t, "acme/cdl.legacy/OriginalService", toldata.AliasSubject("acme", "cdl.legacy.OriginalService"))
+	})
+
+	t.Run("Names", func(t *testing.T) {
+		for _, subject := range []string{"", "cdl.legacy/OriginalService", "cdl.older/FirstService"} {
+			svc := NewRenamedServiceToldataClient(bus)
+			svc.Subject = subject
+
+			resp, err := svc.Echo(ctx, &TestARequest{Input: "a"})
+			assert.Equal(t, nil, err)
+			assert.Equal(t, "a", resp.Output)
+
+			count, err := svc.Count(ctx, &StreamDataRequest{Id: 3})
+			assert.Equal(t, nil, err)
+			data, err := count.Receive()
+			assert.Equal(t, nil, err)
+			assert.Equal(t, int64(3), data.Data)
+
+			info, err := svc.ToldataHealthCheck(ctx, &Empty{})
+			assert.Equal(t, nil, err)
+			assert.Equal(t, "renamed", info.Data)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		assert.Equal(t, map[string]int{
+			"cdl.legacy/OriginalService Echo":               1,
+			"cdl.legacy/OriginalService Count":              1,
+			"cdl.legacy/OriginalService ToldataHealthCheck": 1,
+			"cdl.older/FirstService Echo":                   1,
+			"cdl.older/FirstService Count":                  1,
+			"cdl.older/FirstService ToldataHealthCheck":     1,
+		}, observed)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		subject, _ := micro.ControlSubject(micro.StatsVerb, "cdl_toldatatest_RenamedService", bus.InstanceID())
+		m, err := bus.Connection.Request(subject, nil, time.Second)
+		assert.Equal(t, nil, err)
+		var stats micro.Stats
+		assert.Equal(t, nil, json.Unmarshal(m.Data, &stats))
+		assert.Equal(t, 2, len(stats.Endpoints))
+		for _, e := range stats.Endpoints {
+			var data struct {
+				Aliases map[string]int `json:"aliases"`
+			}
+			assert.Equal(t, nil, json.Unmarshal(e.Data, &data))
+			assert.Equal(t, 3, e.NumRequests)
+			assert.Equal(t, map[string]int{"cdl.legacy/OriginalService": 1, "cdl.older/FirstService": 1}, data.Aliases)
+		}
+	})
+}
@@ -0,0 +1,300 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// authService tells who called Echo
+type authService struct {
+	partialLegacyService
+}
+
+func (s *authService) Echo(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	principal, ok := toldata.PrincipalFromContext(ctx)
+	if !ok {
+		return &TestAResponse{Output: "anonymous"}, nil
+	}
+	return &TestAResponse{Output: principal.Scheme + ":" + principal.Subject}, nil
+}
+
+// signToken signs the claims as a JWT with one of the test keys
+func signToken(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := encode(header) + "." + encode(payload)
+	hash := crypto.SHA256
+	if alg[len(alg)-3:] == "384" {
+		hash = crypto.SHA384
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	var signature []byte
+	var err error
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	}
+	assert.Equal(t, nil, err)
+	return signed + "." + encode(signature)
+}
+
+func TestAuthentication(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Equal(t, nil, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Equal(t, nil, err)
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Equal(t, nil, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Equal(t, nil, err)
+	secret := []byte("secret")
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "EC", "kid": "ec384", "crv": "P-384", "x": encode(ec384Key.X.FillBytes(make([]byte, 48))), "y": encode(ec384Key.Y.FillBytes(make([]byte, 48)))},
+		{"kty": "RSA", "kid": "encryption", "use": "enc", "n": "", "e": ""},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Equal(t, nil, os.WriteFile(path, jwks, 0600))
+	keys, err := toldata.LoadJWKS(path)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(keys))
+	keys["hmac"] = secret
+
+	scheme := toldata.SubjectScheme{Prefix: "auth"}
+	serverBus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{
+		URL:      natsURL,
+		Subjects: scheme,
+		Verifier: toldata.Verifiers{
+			&toldata.JWTVerifier{Keys: keys, Issuer: "toldata-test", Audience: "toldata", Leeway: time.Second},
+			toldata.APIKeys{"key-1": {Subject: "robot"}},
+		},
+	})
+	assert.Equal(t, nil, err)
+	defer serverBus.Close()
+	server := NewLegacyServiceToldataServer(serverBus, &authService{})
+	_, err = server.SubscribeLegacyService()
+	assert.Equal(t, nil, err)
+	serverBus.Connection.Flush()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, Subjects: scheme})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+	svc := NewLegacyServiceToldataClient(bus)
+
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub": "alice",
+			"iss": "toldata-test",
+			"aud": []string{"other", "toldata"},
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}
+		for key, value := range changes {
+			c[key] = value
+		}
+		return c
+	}
+	echo := func(ctx context.Context) (string, error) {
+		resp, err := svc.Echo(ctx, &TestARequest{})
+		if err != nil {
+			return "", err
+		}
+		return resp.Output, nil
+	}
+
+	t.Run("Tokens", func(t *testing.T) {
+		for _, token := range []string{
+			signToken(t, "RS256", "rsa", rsaKey, claims(nil)),
+			signToken(t, "ES256", "ec", ecKey, claims(nil)),
+			signToken(t, "HS256", "hmac", secret, claims(nil)),
+			signToken(t, "ES384", "ec384", ec384Key, claims(nil)),
+			// Tokens without key ID are tried with every key
+			signToken(t, "RS256", "", rsaKey, claims(nil)),
+			signToken(t, "ES256", "", ecKey, claims(nil)),
+			signToken(t, "ES384", "", ec384Key, claims(nil)),
+			signToken(t, "HS384", "", secret, claims(nil)),
+		} {
+			output, err := echo(toldata.WithBearerToken(ctx, token))
+			assert.Equal(t, nil, err)
+			assert.Equal(t, "Bearer:alice", output)
+		}
+
+		output, err := echo(toldata.WithAPIKey(ctx, "key-1"))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "ApiKey:robot", output)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		for _, ctx := range []context.Context{
+			ctx,
+			toldata.WithAPIKey(ctx, "key-2"),
+			toldata.WithAuthorization(ctx, "Basic dXNlcjpwYXNz"),
+			toldata.WithBearerToken(ctx, "not-a-token"),
+			toldata.WithBearerToken(ctx, signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}))),
+			toldata.WithBearerToken(ctx, signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"nbf": time.Now().Add(time.Minute).Unix()}))),
+			toldata.WithBearerToken(ctx, signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": "other"}))),
+			toldata.WithBearerToken(ctx, signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"iss": "other"}))),
+			toldata.WithBearerToken(ctx, signToken(t, "RS256", "ec", rsaKey, claims(nil))),
+			toldata.WithBearerToken(ctx, signToken(t, "HS256", "rsa", secret, claims(nil))),
+			toldata.WithBearerToken(ctx, signToken(t, "none", "", nil, claims(nil))),
+			// The ES algorithms are bound to their curve
+			toldata.WithBearerToken(ctx, signToken(t, "ES256", "ec384", ec384Key, claims(nil))),
+			toldata.WithBearerToken(ctx, signToken(t, "ES384", "ec", ecKey, claims(nil))),
+			toldata.WithBearerToken(ctx, signToken(t, "ES256", "", ec384Key, claims(nil))),
+			toldata.WithBearerToken(ctx, signToken(t, "ES256", "", otherKey, claims(nil))),
+		} {
+			_, err := echo(ctx)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		}
+
+		// Health checks are not authenticated
+		_, err := svc.ToldataHealthCheck(ctx, &Empty{})
+		assert.Equal(t, nil, err)
+
+		count, err := svc.Count(ctx, &StreamDataRequest{Id: 1})
+		assert.Equal(t, nil, count)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Configuration", func(t *testing.T) {
+		bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{
+			URL:        natsURL,
+			Subjects:   scheme,
+			Credential: toldata.Credential{Scheme: toldata.SchemeAPIKey, Value: "key-1"},
+		})
+		assert.Equal(t, nil, err)
+		defer bus.Close()
+		svc := NewLegacyServiceToldataClient(bus)
+
+		resp, err := svc.Echo(ctx, &TestARequest{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "ApiKey:robot", resp.Output)
+
+		count, err := svc.Count(ctx, &StreamDataRequest{Id: 1})
+		assert.Equal(t, nil, err)
+		data, err := count.Receive()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, int64(1), data.Data)
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		anonymous, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{
+			URL:            natsURL,
+			Subjects:       toldata.SubjectScheme{Prefix: "anonymous"},
+			Verifier:       toldata.APIKeys{"key-1": {Subject: "robot"}},
+			AllowAnonymous: true,
+		})
+		assert.Equal(t, nil, err)
+		defer anonymous.Close()
+		server := NewLegacyServiceToldataServer(anonymous, &authService{})
+		_, err = server.SubscribeLegacyService()
+		assert.Equal(t, nil, err)
+
+		svc := NewLegacyServiceToldataClient(anonymous)
+		resp, err := svc.Echo(ctx, &TestARequest{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "anonymous", resp.Output)
+
+		_, err = svc.Echo(toldata.WithAPIKey(ctx, "key-2"), &TestARequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Gateway", func(t *testing.T) {
+		// The credential of the gateway is never given to its callers
+		api, err := NewLegacyServiceREST(ctx, toldata.ServiceConfiguration{
+			URL:        natsURL,
+			Subjects:   scheme,
+			Credential: toldata.Credential{Scheme: toldata.SchemeAPIKey, Value: "key-1"},
+		}, toldata.RESTOptions{})
+		assert.Equal(t, nil, err)
+		defer api.Bus.Close()
+		gateway := httptest.NewServer(api.Handler())
+		defer gateway.Close()
+
+		post := func(header, value string) (int, string) {
+			r, _ := http.NewRequest("POST", gateway.URL+"/api/cdl.toldatatest/LegacyService/Echo", bytes.NewBufferString("{}"))
+			r.Header.Set("Content-Type", "application/json")
+			if header != "" {
+				r.Header.Set(header, value)
+			}
+			resp, err := http.DefaultClient.Do(r)
+			assert.Equal(t, nil, err)
+			defer resp.Body.Close()
+			var output TestAResponse
+			json.NewDecoder(resp.Body).Decode(&output)
+			return resp.StatusCode, output.Output
+		}
+
+		code, output := post("Authorization", "Bearer "+signToken(t, "ES256", "ec", ecKey, claims(nil)))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "Bearer:alice", output)
+
+		code, output = post("X-Api-Key", "key-1")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ApiKey:robot", output)
+
+		code, _ = post("", "")
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		code, _ = post("Authorization", "Bearer")
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		// The gateway still authenticates its own calls
+		resp, err := NewLegacyServiceToldataClient(api.Bus).Echo(ctx, &TestARequest{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "ApiKey:robot", resp.Output)
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+type securedService struct {
+	UnimplementedSecuredServiceToldataServer
+}
+
+func (s *securedService) Read(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	return &TestAResponse{Output: "read"}, nil
+}
+
+func (s *securedService) Delete(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	return &TestAResponse{Output: "deleted"}, nil
+}
+
+func (s *securedService) Feed(req *StreamDataRequest, stream SecuredService_FeedToldataServer) error {
+	return stream.Send(&StreamDataResponse{Data: 1})
+}
+
+func TestAuthorization(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys := toldata.APIKeys{
+		"clerk": {Subject: "carol", Claims: map[string]interface{}{"scope": "orders feed", "roles": []string{"clerk"}}},
+		"admin": {Subject: "ada", Claims: map[string]interface{}{"scp": []interface{}{"orders", "orders:write"}, "roles": "admin"}},
+		"guest": {Subject: "gus", Claims: map[string]interface{}{"scope": "orders"}},
+	}
+
+	var mutex sync.Mutex
+	var denials []toldata.Denial
+	serve := func(prefix string, policy toldata.Policy) *SecuredServiceToldataClient {
+		bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{
+			URL:            natsURL,
+			Subjects:       toldata.SubjectScheme{Prefix: prefix},
+			Verifier:       keys,
+			AllowAnonymous: true,
+			Policy:         policy,
+			OnDenied: func(ctx context.Context, denial toldata.Denial) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				denials = append(denials, denial)
+			},
+		})
+		assert.Equal(t, nil, err)
+		t.Cleanup(bus.Close)
+		server := NewSecuredServiceToldataServer(bus, &securedService{})
+		_, err = server.SubscribeSecuredService()
+		assert.Equal(t, nil, err)
+		return NewSecuredServiceToldataClient(bus)
+	}
+
+	t.Run("Options", func(t *testing.T) {
+		svc := serve("authz", nil)
+		call := func(key string) map[string]codes.Code {
+			ctx := ctx
+			if key != "" {
+				ctx = toldata.WithAPIKey(ctx, key)
+			}
+			result := make(map[string]codes.Code)
+			_, err := svc.Read(ctx, &TestARequest{})
+			result["Read"] = status.Code(err)
+			_, err = svc.Delete(ctx, &TestARequest{})
+			result["Delete"] = status.Code(err)
+			_, err = svc.Feed(ctx, &StreamDataRequest{})
+			result["Feed"] = status.Code(err)
+			return result
+		}
+
+		assert.Equal(t, map[string]codes.Code{"Read": codes.OK, "Delete": codes.PermissionDenied, "Feed": codes.OK}, call("clerk"))
+		assert.Equal(t, map[string]codes.Code{"Read": codes.OK, "Delete": codes.OK, "Feed": codes.PermissionDenied}, call("admin"))
+		assert.Equal(t, map[string]codes.Code{"Read": codes.PermissionDenied, "Delete": codes.PermissionDenied, "Feed": codes.PermissionDenied}, call("guest"))
+		assert.Equal(t, map[string]codes.Code{"Read": codes.PermissionDenied, "Delete": codes.PermissionDenied, "Feed": codes.PermissionDenied}, call(""))
+
+		_, err := svc.ToldataHealthCheck(ctx, &Empty{})
+		assert.Equal(t, nil, err)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		assert.Equal(t, 8, len(denials))
+		assert.Equal(t, "cdl.toldatatest.SecuredService/Delete", denials[0].Requirement.Method)
+		assert.Equal(t, []string{"admin"}, denials[0].Requirement.Roles)
+		assert.Equal(t, "carol", denials[0].Principal.Subject)
+		assert.Equal(t, "missing scope orders:write", denials[0].Reason.Error())
+		assert.Equal(t, "missing scope feed", denials[1].Reason.Error())
+		assert.Equal(t, (*toldata.Principal)(nil), denials[7].Principal)
+		denials = nil
+	})
+
+	t.Run("Policy", func(t *testing.T) {
+		// Reads are open to everyone, the other methods follow the options
+		svc := serve("policy", toldata.PolicyFunc(func(ctx context.Context, principal *toldata.Principal, requirement toldata.Requirement) error {
+			if requirement.Method == "cdl.toldatatest.SecuredService/Read" {
+				return nil
+			}
+			if principal != nil && principal.Subject == "gus" {
+				return errors.New("suspended")
+			}
+			return toldata.DefaultPolicy.Authorize(ctx, principal, requirement)
+		}))
+
+		resp, err := svc.Read(ctx, &TestARequest{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "read", resp.Output)
+
+		resp, err = svc.Delete(toldata.WithAPIKey(ctx, "admin"), &TestARequest{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "deleted", resp.Output)
+
+		_, err = svc.Delete(toldata.WithAPIKey(ctx, "guest"), &TestARequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		assert.Equal(t, 1, len(denials))
+		assert.Equal(t, "suspended", denials[0].Reason.Error())
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	nats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscovery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, id := range []string{"discovery-1", "discovery-2"} {
+		bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, ID: id})
+		assert.Equal(t, nil, err)
+		defer bus.Close()
+		server := NewLegacyServiceToldataServer(bus, &instanceService{id: id})
+		server.Discovery = &toldata.DiscoveryOptions{Version: "1.2.0", Metadata: map[string]string{"zone": "a"}}
+		_, err = server.SubscribeLegacyService()
+		assert.Equal(t, nil, err)
+		bus.Connection.Flush()
+	}
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+	svc := NewLegacyServiceToldataClient(bus)
+
+	t.Run("Discover", func(t *testing.T) {
+		instances, err := toldata.Discover(ctx, bus, "cdl_toldatatest_LegacyService")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, len(instances))
+		for i, info := range instances {
+			assert.Equal(t, []string{"discovery-1", "discovery-2"}[i], info.ID)
+			assert.Equal(t, "1.2.0", info.Version)
+			assert.Equal(t, "cdl.toldatatest.LegacyService", info.Metadata[toldata.MetadataService])
+			assert.Equal(t, "a", info.Metadata["zone"])
+			assert.Equal(t, 3, len(info.Endpoints))
+			assert.Equal(t, "Echo", info.Endpoints[0].Name)
+		}
+
+		timeout, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		instances, err = toldata.Discover(timeout, bus, "")
+		assert.Equal(t, nil, err)
+		assert.True(t, len(instances) >= 2)
+
+		instances, err = toldata.Discover(timeout, bus, "unknown")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 0, len(instances))
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, err := svc.Echo(ctx, &TestARequest{Input: "stats"})
+			assert.Equal(t, nil, err)
+		}
+		_, err := svc.EchoAll(ctx, &TestARequest{Input: "stats"}, toldata.GatherOptions{Expected: 2})
+		assert.Equal(t, nil, err)
+
+		// A request which can not be decoded fails on every server
+		instances, err := toldata.Discover(ctx, bus, "cdl_toldatatest_LegacyService")
+		assert.Equal(t, nil, err)
+		_, err = bus.Gather(ctx, instances[0].Endpoints[0].Subject, []byte{0xff}, toldata.GatherOptions{Expected: 2})
+		assert.Equal(t, nil, err)
+
+		requests := 0
+		for _, id := range []string{"discovery-1", "discovery-2"} {
+			subject, err := micro.ControlSubject(micro.StatsVerb, "cdl_toldatatest_LegacyService", id)
+			assert.Equal(t, nil, err)
+			m, err := bus.Connection.Request(subject, nil, time.Second)
+			assert.Equal(t, nil, err)
+
+			var stats micro.Stats
+			assert.Equal(t, nil, json.Unmarshal(m.Data, &stats))
+			assert.Equal(t, micro.StatsResponseType, stats.Type)
+			assert.Equal(t, id, stats.ID)
+			assert.Equal(t, "Echo", stats.Endpoints[0].Name)
+			assert.Equal(t, 1, stats.Endpoints[0].NumErrors)
+			assert.NotEqual(t, "", stats.Endpoints[0].LastError)
+			requests += stats.Endpoints[0].NumRequests
+		}
+		assert.Equal(t, 7, requests)
+	})
+
+	t.Run("Ping", func(t *testing.T) {
+		subject, err := micro.ControlSubject(micro.PingVerb, "cdl_toldatatest_LegacyService", "discovery-2")
+		assert.Equal(t, nil, err)
+		m, err := bus.Connection.Request(subject, nil, time.Second)
+		assert.Equal(t, nil, err)
+
+		var ping micro.Ping
+		assert.Equal(t, nil, json.Unmarshal(m.Data, &ping))
+		assert.Equal(t, micro.PingResponseType, ping.Type)
+		assert.Equal(t, "discovery-2", ping.ID)
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		server := NewLegacyServiceToldataServer(bus, &instanceService{})
+		server.Discovery = &toldata.DiscoveryOptions{Name: "not valid"}
+		_, err := server.SubscribeLegacyService()
+		assert.NotEqual(t, nil, err)
+	})
+
+	t.Run("SubscribeError", func(t *testing.T) {
+		// Count can not be subscribed, the subscriptions of Echo and the
+		// registration are undone
+		broken, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, ID: "discovery-3", Subjects: toldata.SubjectScheme{
+			Format: func(service, method string) string {
+				if method == "Count" {
+					return "broken count"
+				}
+				return "broken." + method
+			},
+		}})
+		assert.Equal(t, nil, err)
+		defer broken.Close()
+		server := NewLegacyServiceToldataServer(broken, &instanceService{id: "discovery-3"})
+		server.Discovery = &toldata.DiscoveryOptions{}
+		done, err := server.SubscribeLegacyService()
+		assert.NotEqual(t, nil, err)
+		assert.Equal(t, (<-chan struct{})(nil), done)
+		broken.Connection.Flush()
+
+		_, err = bus.Connection.Request("broken.Echo", nil, 200*time.Millisecond)
+		assert.Equal(t, nats.ErrNoResponders, err)
+		instances, err := toldata.Discover(ctx, bus, "cdl_toldatatest_LegacyService")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, len(instances))
+	})
+}
@@ -0,0 +1,231 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	nats "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+type durableService struct {
+	UnimplementedDurableServiceToldataServer
+	mu        sync.Mutex
+	attempts  map[string]int
+	processed chan string
+}
+
+func (s *durableService) Process(ctx context.Context, req *TestARequest) (*Empty, error) {
+	s.mu.Lock()
+	s.attempts[req.Input]++
+	attempt := s.attempts[req.Input]
+	s.mu.Unlock()
+
+	switch {
+	case req.Input == "poison":
+		return nil, errors.New("poison")
+	case req.Input == "flaky" && attempt < 3:
+		return nil, errors.New("flaky")
+	}
+	s.processed <- req.Input
+	return &Empty{}, nil
+}
+
+func (s *durableService) attemptsOf(input string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[input]
+}
+
+// startDurableServer subscribes a DurableService server until ctx is done
+func startDurableServer(t *testing.T, ctx context.Context, impl *durableService) {
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	t.Cleanup(bus.Close)
+
+	server := NewDurableServiceToldataServer(bus, impl)
+	server.Durable = toldata.DurableOptions{MaxDeliver: 3, Backoff: []time.Duration{50 * time.Millisecond, 100 * time.Millisecond}}
+	_, err = server.SubscribeDurableService()
+	assert.Equal(t, nil, err)
+}
+
+func TestDurable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, Retries: 10})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+	svc := NewDurableServiceToldataClient(bus)
+
+	// Starts from an empty stream when the server is kept between runs
+	js, err := bus.Connection.JetStream()
+	assert.Equal(t, nil, err)
+	js.DeleteStream(toldata.DurableStreamName("cdl.toldatatest/DurableService"))
+
+	impl := &durableService{attempts: make(map[string]int), processed: make(chan string, 10)}
+
+	// The calls are stored while no server is running
+	serverCtx, stop := context.WithCancel(ctx)
+	startDurableServer(t, serverCtx, impl)
+	stop()
+	// Leaves the pending fetch of the stopped server expire
+	time.Sleep(1500 * time.Millisecond)
+
+	for _, input := range []string{"a", "b"} {
+		_, err := svc.Process(ctx, &TestARequest{Input: input})
+		assert.Equal(t, nil, err)
+	}
+
+	serverCtx, stop = context.WithCancel(ctx)
+	defer stop()
+	startDurableServer(t, serverCtx, impl)
+	assert.ElementsMatch(t, []string{"a", "b"}, receivedEvents(impl.processed))
+
+	t.Run("Redelivery", func(t *testing.T) {
+		_, err := svc.Process(ctx, &TestARequest{Input: "flaky"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{"flaky"}, receivedEvents(impl.processed))
+		assert.Equal(t, 3, impl.attemptsOf("flaky"))
+	})
+
+	t.Run("DeadLetter", func(t *testing.T) {
+		headers := make(chan []string, 1)
+		sub, err := bus.Connection.Subscribe(toldata.DeadLetterSubject("cdl.toldatatest/DurableService/Process"), func(m *nats.Msg) {
+			headers <- []string{m.Header.Get(toldata.HeaderError), m.Header.Get(toldata.HeaderDeliveries), m.Header.Get(toldata.HeaderProtocol)}
+		})
+		assert.Equal(t, nil, err)
+		defer sub.Unsubscribe()
+
+		peerCtx := toldata.NewPeerContext(ctx, &toldata.Peer{Addr: &net.IPAddr{IP: net.ParseIP("192.0.2.1")}, Protocol: "test"})
+		_, err = svc.Process(peerCtx, &TestARequest{Input: "poison"})
+		assert.Equal(t, nil, err)
+		select {
+		case h := <-headers:
+			assert.Equal(t, []string{"poison", "3", "test"}, h)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the call was not moved to the dead letter subject")
+		}
+		assert.Equal(t, 3, impl.attemptsOf("poison"))
+	})
+
+	t.Run("Deduplication", func(t *testing.T) {
+		idCtx := toldata.WithMessageID(ctx, "order-1")
+		for i := 0; i < 3; i++ {
+			_, err := svc.Process(idCtx, &TestARequest{Input: "order"})
+			assert.Equal(t, nil, err)
+		}
+		assert.Equal(t, []string{"order"}, receivedEvents(impl.processed))
+	})
+
+	t.Run("Gateway", func(t *testing.T) {
+		rest, err := NewDurableServiceREST(ctx, toldata.ServiceConfiguration{URL: natsURL}, toldata.RESTOptions{})
+		assert.Equal(t, nil, err)
+		httpServer := httptest.NewServer(rest.Handler())
+		defer httpServer.Close()
+		resp, err := http.Post(httpServer.URL+"/api/cdl.toldatatest/DurableService/Process", toldata.ContentTypeJSON, bytes.NewBufferString(`{"input": "rest"}`))
+		assert.Equal(t, nil, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"rest"}, receivedEvents(impl.processed))
+	})
+
+	t.Run("ChangedOptions", func(t *testing.T) {
+		stream := toldata.DurableStreamName("cdl.toldatatest/DurableService")
+		consumer := "cdl_toldatatest_DurableService_Process"
+		info, err := js.ConsumerInfo(stream, consumer)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 30*time.Second, info.Config.AckWait)
+
+		// A server restarted with other options updates the consumer
+		serverBus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+		assert.Equal(t, nil, err)
+		defer serverBus.Close()
+		server := NewDurableServiceToldataServer(serverBus, impl)
+		server.Durable = toldata.DurableOptions{AckWait: 10 * time.Second}
+		_, err = server.SubscribeDurableService()
+		assert.Equal(t, nil, err)
+
+		info, err = js.ConsumerInfo(stream, consumer)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 10*time.Second, info.Config.AckWait)
+	})
+
+	t.Run("Alias", func(t *testing.T) {
+		// The calls made on an alias are stored in the stream named after it
+		alias := "cdl.legacy/DurableService"
+		js.DeleteStream(toldata.DurableStreamName(alias))
+
+		serverBus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+		assert.Equal(t, nil, err)
+		defer serverBus.Close()
+		server := NewDurableServiceToldataServer(serverBus, impl)
+		server.Aliases = []string{alias}
+		aliased := make(chan string, 1)
+		server.OnAlias = func(alias, method string) {
+			aliased <- alias + ":" + method
+		}
+		_, err = server.SubscribeDurableService()
+		assert.Equal(t, nil, err)
+
+		client := NewDurableServiceToldataClient(bus)
+		client.Subject = alias
+		_, err = client.Process(ctx, &TestARequest{Input: "aliased"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{"aliased"}, receivedEvents(impl.processed))
+		assert.Equal(t, []string{alias + ":Process"}, receivedEvents(aliased))
+	})
+
+	t.Run("PreviousSubjects", func(t *testing.T) {
+		// Only the migrated servers are left
+		stop()
+		time.Sleep(1500 * time.Millisecond)
+
+		for _, scheme := range []toldata.SubjectScheme{
+			// The calls of the previous scheme are kept in its stream
+			{Prefix: "acme", Separator: "."},
+			// Both schemes store the calls in the same stream
+			{Separator: "."},
+		} {
+			serverBus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, Subjects: scheme, PreviousSubjects: &toldata.SubjectScheme{}})
+			assert.Equal(t, nil, err)
+			defer serverBus.Close()
+			_, err = NewDurableServiceToldataServer(serverBus, impl).SubscribeDurableService()
+			assert.Equal(t, nil, err)
+
+			clientBus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, Subjects: scheme})
+			assert.Equal(t, nil, err)
+			defer clientBus.Close()
+			_, err = NewDurableServiceToldataClient(clientBus).Process(ctx, &TestARequest{Input: "migrated"})
+			assert.Equal(t, nil, err)
+			_, err = svc.Process(ctx, &TestARequest{Input: "previous"})
+			assert.Equal(t, nil, err)
+			assert.ElementsMatch(t, []string{"migrated", "previous"}, receivedEvents(impl.processed))
+		}
+
+		info, err := js.ConsumerInfo(toldata.DurableStreamName("cdl.toldatatest/DurableService"), "cdl_toldatatest_DurableService_Process")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{"cdl.toldatatest/DurableService/Process", "cdl.toldatatest.DurableService.Process"}, info.Config.FilterSubjects)
+	})
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/citradigital/toldata/gateway"
+	"github.com/stretchr/testify/assert"
+)
+
+type eventService struct {
+	UnimplementedEventServiceToldataServer
+	name    string
+	created chan string
+}
+
+func (s *eventService) Created(ctx context.Context, req *TestARequest) (*Empty, error) {
+	protocol := ""
+	if peerInfo, ok := toldata.PeerFromContext(ctx); ok {
+		protocol = peerInfo.Protocol
+	}
+	s.created <- s.name + ":" + req.Input + ":" + protocol
+	return &Empty{}, nil
+}
+
+// subscribeEvents starts count servers sharing the channel of the received events
+func subscribeEvents(t *testing.T, ctx context.Context, delivery toldata.EventDelivery, count int) chan string {
+	created := make(chan string, 10)
+	for i := 0; i < count; i++ {
+		bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+		assert.Equal(t, nil, err)
+		t.Cleanup(bus.Close)
+
+		server := NewEventServiceToldataServer(bus, &eventService{name: string(rune('a' + i)), created: created})
+		server.EventDelivery = delivery
+		_, err = server.SubscribeEventService()
+		assert.Equal(t, nil, err)
+		bus.Connection.Flush()
+	}
+	return created
+}
+
+// receivedEvents returns the events received until none arrives for a while
+func receivedEvents(created chan string) []string {
+	var events []string
+	for {
+		select {
+		case event := <-created:
+			events = append(events, event)
+		case <-time.After(300 * time.Millisecond):
+			return events
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+	svc := NewEventServiceToldataClient(bus)
+
+	t.Run("Broadcast", func(t *testing.T) {
+		created := subscribeEvents(t, ctx, toldata.BroadcastDelivery, 2)
+		err := svc.PublishCreated(toldata.NewPeerContext(ctx, &toldata.Peer{Addr: &net.IPAddr{IP: net.ParseIP("192.0.2.1")}, Protocol: "test"}), &TestARequest{Input: "x"})
+		assert.Equal(t, nil, err)
+		assert.ElementsMatch(t, []string{"a:x:test", "b:x:test"}, receivedEvents(created))
+
+		assert.Equal(t, toldata.ErrEmptyRequest, svc.PublishCreated(ctx, nil))
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		created := subscribeEvents(t, ctx, toldata.QueueDelivery, 2)
+		for i := 0; i < 4; i++ {
+			err := svc.PublishCreated(ctx, &TestARequest{Input: "y"})
+			assert.Equal(t, nil, err)
+		}
+		assert.Equal(t, 4, len(receivedEvents(created)))
+	})
+
+	t.Run("REST", func(t *testing.T) {
+		created := subscribeEvents(t, ctx, toldata.BroadcastDelivery, 1)
+
+		rest, err := NewEventServiceREST(ctx, toldata.ServiceConfiguration{URL: natsURL}, toldata.RESTOptions{})
+		assert.Equal(t, nil, err)
+		httpServer := httptest.NewServer(rest.Handler())
+		defer httpServer.Close()
+		resp, err := http.Post(httpServer.URL+"/api/cdl.toldatatest/EventService/Created", toldata.ContentTypeJSON, bytes.NewBufferString(`{"input": "rest"}`))
+		assert.Equal(t, nil, err)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{}`, string(body))
+		assert.Equal(t, []string{"a:rest:http"}, receivedEvents(created))
+	})
+
+	t.Run("DynamicGateway", func(t *testing.T) {
+		created := subscribeEvents(t, ctx, toldata.BroadcastDelivery, 1)
+
+		dir, err := ioutil.TempDir("", "toldata-events")
+		assert.Equal(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		g, err := gateway.New(ctx, gateway.Config{
+			Descriptors: []string{writeDescriptorSet(t, dir)},
+			Services:    []string{"cdl.toldatatest.EventService"},
+			NATS:        gateway.NATSConfig{URL: natsURL},
+		})
+		assert.Equal(t, nil, err)
+		defer g.Close()
+		assert.Equal(t, true, gateway.Event(g.Services[0].Methods().ByName("Created")))
+
+		httpServer := httptest.NewServer(g.RESTHandler())
+		defer httpServer.Close()
+		resp, err := http.Post(httpServer.URL+"/api/cdl.toldatatest/EventService/Created", toldata.ContentTypeJSON, bytes.NewBufferString(`{"input": "dynamic"}`))
+		assert.Equal(t, nil, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"a:dynamic:http"}, receivedEvents(created))
+	})
+
+	t.Run("Mock", func(t *testing.T) {
+		mock := &EventServiceToldataClientMock{}
+		assert.Equal(t, nil, mock.PublishCreated(ctx, &TestARequest{Input: "m"}))
+		mock.ReturnPublishCreated(errors.New("down"))
+		assert.NotEqual(t, nil, mock.PublishCreated(ctx, &TestARequest{Input: "n"}))
+		assert.Equal(t, 2, len(mock.CallsTo("PublishCreated")))
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/citradigital/toldata"
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	golangproto "github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The messages and the encoding of the gogo flavour. test/golang runs the
+// same tests with the messages of protoc-gen-go, the tests shared by both
+// only use the names below.
+type (
+	Empty                  = toldata.Empty
+	ToldataHealthCheckInfo = toldata.ToldataHealthCheckInfo
+	ErrorMessage           = toldata.ErrorMessage
+
+	EmptyProto        = types.Empty
+	Timestamp         = types.Timestamp
+	Struct            = types.Struct
+	Value             = types.Value
+	Value_StringValue = types.Value_StringValue
+	Value_NumberValue = types.Value_NumberValue
+	StringValue       = types.StringValue
+	Int64Value        = types.Int64Value
+)
+
+var (
+	marshal       = gogoproto.Marshal
+	unmarshal     = gogoproto.Unmarshal
+	durationProto = types.DurationProto
+)
+
+// registeredFile returns the descriptor of a file compiled into the test binary
+func registeredFile(t *testing.T, name string) *descriptorpb.FileDescriptorProto {
+	gz := gogoproto.FileDescriptor(name)
+	if gz == nil {
+		gz = golangproto.FileDescriptor(name)
+	}
+	if gz == nil {
+		gz = gogoproto.FileDescriptor(path.Base(name))
+	}
+	assert.NotEqual(t, 0, len(gz), name)
+
+	reader, err := gzip.NewReader(bytes.NewReader(gz))
+	assert.Equal(t, nil, err)
+	data, err := ioutil.ReadAll(reader)
+	assert.Equal(t, nil, err)
+
+	fd := &descriptorpb.FileDescriptorProto{}
+	err = proto.Unmarshal(data, fd)
+	assert.Equal(t, nil, err)
+	fd.Name = proto.String(name)
+	return fd
+}
+
+// writeDescriptorSet writes the set protoc --include_imports would create for the test proto
+func writeDescriptorSet(t *testing.T, dir string) string {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+
+	var add func(name string)
+	add = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		fd := registeredFile(t, name)
+		for _, dependency := range fd.Dependency {
+			add(dependency)
+		}
+		set.File = append(set.File, fd)
+	}
+	add("toldata_test.proto")
+
+	data, err := proto.Marshal(set)
+	assert.Equal(t, nil, err)
+
+	file := filepath.Join(dir, "toldata_test.pb")
+	err = ioutil.WriteFile(file, data, 0644)
+	assert.Equal(t, nil, err)
+	return file
+}
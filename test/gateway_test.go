@@ -0,0 +1,222 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/citradigital/toldata/gateway"
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	status "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	gatewayGRPCAddr = "localhost:21003"
+	gatewayRESTAddr = "localhost:21004"
+)
+
+func TestGateway(t *testing.T) {
+	dir, err := ioutil.TempDir("", "toldata-gateway")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "gateway.json")
+	err = ioutil.WriteFile(configFile, []byte(`{
+		"descriptors": ["`+writeDescriptorSet(t, dir)+`"],
+		"services": ["cdl.toldatatest.TestService"],
+		"trusted_proxies": ["127.0.0.1", "::1"],
+		"nats": {"url": "`+natsURL+`", "id": "dynamic-gateway", "reconnect_wait": "1s"},
+		"grpc": {"listen": "`+gatewayGRPCAddr+`"},
+		"rest": {"listen": "`+gatewayRESTAddr+`", "max_body_size": 1024}
+	}`), 0644)
+	assert.Equal(t, nil, err)
+
+	config, err := gateway.LoadConfig(configFile)
+	assert.Equal(t, nil, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g, err := gateway.New(ctx, *config)
+	assert.Equal(t, nil, err)
+	defer g.Close()
+	assert.Equal(t, 1, len(g.Services))
+
+	done := make(chan error)
+	go func() {
+		done <- g.Run(ctx)
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	conn, err := grpc.Dial(gatewayGRPCAddr, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(time.Second))
+	assert.Equal(t, nil, err)
+	defer conn.Close()
+	client := NewTestServiceClient(conn)
+
+	t.Run("GRPCUnary", func(t *testing.T) {
+		resp, err := client.GetTestA(ctx, &TestARequest{Input: "Dynamic"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "OKDynamic", resp.Output)
+
+		_, err = client.GetTestA(ctx, &TestARequest{Input: "not-found"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("GRPCPeer", func(t *testing.T) {
+		resp, err := client.GetTestGetIP(ctx, &Empty{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "127.0.0.1", resp.Ip)
+		assert.Equal(t, "dynamic-gateway", resp.Gateway)
+		assert.Equal(t, "grpc", resp.Protocol)
+	})
+
+	t.Run("GRPCServerStream", func(t *testing.T) {
+		stream, err := client.StreamDataAlt1(ctx, &StreamDataRequest{Id: 5})
+		assert.Equal(t, nil, err)
+		count := 0
+		for {
+			_, err = stream.Recv()
+			if err != nil {
+				break
+			}
+			count++
+		}
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, 5, count)
+	})
+
+	t.Run("GRPCClientStream", func(t *testing.T) {
+		stream, err := client.FeedData(ctx)
+		assert.Equal(t, nil, err)
+		for i := 0; i < 10; i++ {
+			err = stream.Send(&FeedDataRequest{Data: int64(i)})
+			assert.Equal(t, nil, err)
+		}
+		resp, err := stream.CloseAndRecv()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, int64(45), resp.Sum)
+	})
+
+	t.Run("GRPCHealth", func(t *testing.T) {
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "cdl.toldatatest.TestService"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	})
+
+	t.Run("GRPCReflection", func(t *testing.T) {
+		stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		assert.Equal(t, nil, err)
+		err = stream.Send(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "cdl.toldatatest.TestService"},
+		})
+		assert.Equal(t, nil, err)
+		resp, err := stream.Recv()
+		assert.Equal(t, nil, err)
+		stream.CloseSend()
+
+		// The descriptors come from the descriptor set
+		files := resp.GetFileDescriptorResponse().GetFileDescriptorProto()
+		assert.NotEqual(t, 0, len(files))
+		fd := &descriptorpb.FileDescriptorProto{}
+		err = proto.Unmarshal(files[0], fd)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "toldata_test.proto", fd.GetName())
+	})
+
+	t.Run("REST", func(t *testing.T) {
+		url := "http://" + gatewayRESTAddr + "/api/test/cdl.toldatatest/TestService/GetTestA"
+
+		resp, err := http.Post(url, "application/json", bytes.NewBufferString(`{"input": "REST"}`))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var output map[string]string
+		err = json.NewDecoder(resp.Body).Decode(&output)
+		resp.Body.Close()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "OKREST", output["output"])
+
+		raw, err := gogoproto.Marshal(&TestARequest{Input: "Proto"})
+		assert.Equal(t, nil, err)
+		resp, err = http.Post(url, toldata.ContentTypeProtobuf, bytes.NewReader(raw))
+		assert.Equal(t, nil, err)
+		raw, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, toldata.ContentTypeProtobuf, resp.Header.Get("Content-Type"))
+		protoOutput := &TestAResponse{}
+		err = gogoproto.Unmarshal(raw, protoOutput)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "OKProto", protoOutput.Output)
+
+		resp, err = http.Post(url, "application/json", bytes.NewBufferString(`{"unknown": 1}`))
+		assert.Equal(t, nil, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, err = http.Post(url, "application/json", bytes.NewBufferString(`{"input": "`+string(make([]byte, 2048))+`"}`))
+		assert.Equal(t, nil, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+		// Streaming methods have no REST route
+		resp, err = http.Post("http://"+gatewayRESTAddr+"/api/test/cdl.toldatatest/TestService/StreamData", "application/json", bytes.NewBufferString(`{}`))
+		assert.Equal(t, nil, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	cancel()
+	assert.Equal(t, nil, <-done)
+}
+
+func TestGatewayConfig(t *testing.T) {
+	_, err := gateway.LoadConfig("does-not-exist.json")
+	assert.NotEqual(t, nil, err)
+
+	config := gateway.Config{NATS: gateway.NATSConfig{URL: natsURL}, GRPC: gateway.GRPCConfig{Listen: ":0"}}
+	assert.NotEqual(t, nil, config.Validate())
+
+	config.Descriptors = []string{"api.pb"}
+	assert.Equal(t, nil, config.Validate())
+
+	config.GRPC.Listen = ""
+	assert.NotEqual(t, nil, config.Validate())
+
+	dir, err := ioutil.TempDir("", "toldata-gateway")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	config = gateway.Config{
+		Descriptors: []string{writeDescriptorSet(t, dir)},
+		Services:    []string{"cdl.toldatatest.NoService"},
+		NATS:        gateway.NATSConfig{URL: natsURL},
+	}
+	_, err = gateway.New(context.Background(), config)
+	assert.NotEqual(t, nil, err)
+}
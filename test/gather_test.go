@@ -0,0 +1,113 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// instanceService tells which instance handled Echo
+type instanceService struct {
+	UnimplementedLegacyServiceToldataServer
+	id string
+}
+
+func (s *instanceService) Echo(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	if req.Input == "fail" && s.id == "gather-2" {
+		return nil, status.Error(codes.NotFound, "fail")
+	}
+	return &TestAResponse{Output: s.id + ":" + req.Input}, nil
+}
+
+func TestGather(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, id := range []string{"gather-1", "gather-2", "gather-3"} {
+		bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, ID: id})
+		assert.Equal(t, nil, err)
+		defer bus.Close()
+		server := NewLegacyServiceToldataServer(bus, &instanceService{id: id})
+		_, err = server.SubscribeLegacyService()
+		assert.Equal(t, nil, err)
+		bus.Connection.Flush()
+	}
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+	svc := NewLegacyServiceToldataClient(bus)
+
+	t.Run("Expected", func(t *testing.T) {
+		replies, err := svc.EchoAll(ctx, &TestARequest{Input: "x"}, toldata.GatherOptions{Expected: 3})
+		assert.Equal(t, nil, err)
+		var outputs []string
+		for _, reply := range replies {
+			assert.Equal(t, nil, reply.Err)
+			assert.Equal(t, reply.BusID+":x", reply.Response.Output)
+			outputs = append(outputs, reply.Response.Output)
+		}
+		assert.ElementsMatch(t, []string{"gather-1:x", "gather-2:x", "gather-3:x"}, outputs)
+
+		replies, err = svc.EchoAll(ctx, &TestARequest{Input: "y"}, toldata.GatherOptions{Expected: 4, Timeout: 200 * time.Millisecond})
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+		assert.Equal(t, 3, len(replies))
+	})
+
+	t.Run("First", func(t *testing.T) {
+		replies, err := svc.EchoAll(ctx, &TestARequest{Input: "z"}, toldata.GatherOptions{First: 1})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 1, len(replies))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		start := time.Now()
+		replies, err := svc.EchoAll(ctx, &TestARequest{Input: "fail"}, toldata.GatherOptions{Timeout: 200 * time.Millisecond})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 3, len(replies))
+		assert.True(t, time.Since(start) >= 200*time.Millisecond)
+
+		for _, reply := range replies {
+			if reply.BusID == "gather-2" {
+				assert.Equal(t, codes.NotFound, status.Code(reply.Err))
+				assert.Nil(t, reply.Response)
+			} else {
+				assert.Equal(t, nil, reply.Err)
+			}
+		}
+	})
+
+	t.Run("HealthCheck", func(t *testing.T) {
+		replies, err := svc.ToldataHealthCheckAll(ctx, &Empty{}, toldata.GatherOptions{Expected: 3})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 3, len(replies))
+	})
+
+	t.Run("Mock", func(t *testing.T) {
+		mock := &LegacyServiceToldataClientMock{}
+		mock.ReturnEcho(&TestAResponse{Output: "mocked"}, nil)
+		replies, err := mock.EchoAll(ctx, &TestARequest{Input: "m"}, toldata.GatherOptions{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 1, len(replies))
+		assert.Equal(t, toldata.MockBusID, replies[0].BusID)
+		assert.Equal(t, "mocked", replies[0].Response.Output)
+	})
+}
@@ -0,0 +1 @@
+../aliases_test.go
\ No newline at end of file
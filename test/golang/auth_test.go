@@ -0,0 +1 @@
+../auth_test.go
\ No newline at end of file
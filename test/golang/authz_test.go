@@ -0,0 +1 @@
+../authz_test.go
\ No newline at end of file
@@ -0,0 +1 @@
+../base_test.go
\ No newline at end of file
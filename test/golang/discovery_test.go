@@ -0,0 +1 @@
+../discovery_test.go
\ No newline at end of file
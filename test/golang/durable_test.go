@@ -0,0 +1 @@
+../durable_test.go
\ No newline at end of file
@@ -0,0 +1 @@
+../events_test.go
\ No newline at end of file
@@ -0,0 +1 @@
+../fixtures.go
\ No newline at end of file
@@ -0,0 +1,87 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/citradigital/toldata/toldatapb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// The messages and the encoding of the protoc-gen-go flavour, generated with
+// messages=golang. The tests linked from the parent directory run with them,
+// the ones using the gRPC plugins are gogo-only.
+type (
+	Empty                  = toldatapb.Empty
+	ToldataHealthCheckInfo = toldatapb.ToldataHealthCheckInfo
+	ErrorMessage           = toldatapb.ErrorMessage
+
+	EmptyProto        = emptypb.Empty
+	Timestamp         = timestamppb.Timestamp
+	Struct            = structpb.Struct
+	Value             = structpb.Value
+	Value_StringValue = structpb.Value_StringValue
+	Value_NumberValue = structpb.Value_NumberValue
+	StringValue       = wrapperspb.StringValue
+	Int64Value        = wrapperspb.Int64Value
+)
+
+var (
+	marshal       = proto.Marshal
+	unmarshal     = proto.Unmarshal
+	durationProto = durationpb.New
+)
+
+// writeDescriptorSet writes the set protoc --include_imports would create for the test proto
+func writeDescriptorSet(t *testing.T, dir string) string {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+
+	var add func(file protoreflect.FileDescriptor)
+	add = func(file protoreflect.FileDescriptor) {
+		if seen[file.Path()] {
+			return
+		}
+		seen[file.Path()] = true
+		imports := file.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+	file, err := protoregistry.GlobalFiles.FindFileByPath("toldata_test.proto")
+	assert.Equal(t, nil, err)
+	add(file)
+
+	data, err := proto.Marshal(set)
+	assert.Equal(t, nil, err)
+
+	path := filepath.Join(dir, "toldata_test.pb")
+	err = ioutil.WriteFile(path, data, 0644)
+	assert.Equal(t, nil, err)
+	return path
+}
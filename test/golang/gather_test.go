@@ -0,0 +1 @@
+../gather_test.go
\ No newline at end of file
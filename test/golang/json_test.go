@@ -0,0 +1 @@
+../json_test.go
\ No newline at end of file
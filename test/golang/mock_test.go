@@ -0,0 +1 @@
+../mock_test.go
\ No newline at end of file
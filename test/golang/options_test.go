@@ -0,0 +1 @@
+../options_test.go
\ No newline at end of file
@@ -0,0 +1 @@
+../peer_test.go
\ No newline at end of file
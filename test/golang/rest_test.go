@@ -0,0 +1 @@
+../rest_test.go
\ No newline at end of file
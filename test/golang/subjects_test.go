@@ -0,0 +1 @@
+../subjects_test.go
\ No newline at end of file
@@ -0,0 +1 @@
+../toldata_init_test.go
\ No newline at end of file
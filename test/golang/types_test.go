@@ -0,0 +1 @@
+../types_test.go
\ No newline at end of file
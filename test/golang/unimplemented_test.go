@@ -0,0 +1 @@
+../unimplemented_test.go
\ No newline at end of file
@@ -0,0 +1 @@
+../version_test.go
\ No newline at end of file
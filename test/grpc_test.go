@@ -2,6 +2,7 @@ package test
 
 import (
 	"context"
+	"errors"
 	io "io"
 	"log"
 	"net"
@@ -9,13 +10,24 @@ import (
 	"time"
 
 	"github.com/citradigital/toldata"
+	proto "github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	nats "github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	status "google.golang.org/grpc/status"
 )
 
 var grpcServer *grpc.Server
+var grpcAPI *TestServiceGRPC
 var grpcClient TestServiceClient
+var grpcConn *grpc.ClientConn
 
 const serverAddr = "localhost:21001"
 
@@ -26,12 +38,21 @@ func startTestServer(grpcServer *grpc.Server) {
 	}
 
 	ctx := context.Background()
-	api, err := NewTestServiceGRPC(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	proxies, err := toldata.NewTrustedProxies("127.0.0.1", "::1")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	api, err := NewTestServiceGRPC(ctx, toldata.ServiceConfiguration{URL: natsURL, ID: "grpc-gateway"}, toldata.GRPCOptions{
+		TrustedProxies: proxies,
+	})
 	if err != nil {
 		log.Fatalln("Failed to create Toldata service")
 	}
 
+	grpcAPI = api
 	RegisterTestServiceServer(grpcServer, api)
+	toldata.RegisterGRPCServices(grpcServer, toldata.HealthOptions{}, api)
 	log.Println("Starting GRPC server...")
 	grpcServer.Serve(lis)
 }
@@ -52,6 +73,7 @@ func TestGRPCInit(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	grpcConn = conn
 	grpcClient = NewTestServiceClient(conn)
 	log.Println("GRPC connected")
 }
@@ -116,3 +138,272 @@ func TestGRPCFeedDataHappy(t *testing.T) {
 	assert.Equal(t, int64(45), resp.Sum)
 
 }
+
+func TestGRPCGetIP(t *testing.T) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-forwarded-for", "203.0.113.9")
+
+	res, err := grpcClient.GetTestGetIP(ctx, &toldata.Empty{})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "203.0.113.9", res.Ip)
+	assert.Equal(t, "grpc-gateway", res.Gateway)
+	assert.Equal(t, "grpc", res.Protocol)
+	assert.Contains(t, res.UserAgent, "grpc-go")
+}
+
+func TestGRPCStatusHandlerError(t *testing.T) {
+	_, err := grpcClient.GetTestA(context.Background(), &TestARequest{Input: "123456"})
+
+	st, ok := status.FromError(err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, codes.Unknown, st.Code())
+	assert.Equal(t, "test-error-1", st.Message())
+}
+
+func TestGRPCStatusCarried(t *testing.T) {
+	_, err := grpcClient.GetTestA(context.Background(), &TestARequest{Input: "not-found"})
+
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "missing", st.Message())
+}
+
+func TestGRPCStatusDetails(t *testing.T) {
+	_, err := grpcClient.GetTestA(context.Background(), &TestARequest{Input: "details"})
+
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "bad-input", st.Message())
+
+	details := st.Details()
+	assert.Equal(t, 1, len(details))
+
+	badRequest, ok := details[0].(*errdetails.BadRequest)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "input", badRequest.FieldViolations[0].Field)
+}
+
+func TestGRPCStatusDeadlineExceeded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, err := grpcAPI.GetTestAB(ctx, &TestARequest{Input: "slow"})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestGRPCStatusCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := grpcAPI.GetTestA(ctx, &TestARequest{Input: "canceled"})
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
+
+func TestGRPCStatusUnavailable(t *testing.T) {
+	ctx := context.Background()
+
+	// Nobody serves this subject, NATS reports that there are no responders
+	_, err := grpcAPI.Bus.Request(ctx, "cdl.toldatatest/NoService/NoMethod", nil)
+	assert.NotEqual(t, nil, err)
+
+	err = toldata.RequestError("cdl.toldatatest/NoService/NoMethod", err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, true, errors.Is(err, nats.ErrNoResponders))
+}
+
+func TestGRPCStreamEnd(t *testing.T) {
+	stream, err := grpcClient.StreamDataAlt1(context.Background(), &StreamDataRequest{Id: 5})
+	assert.Equal(t, nil, err)
+
+	count := 0
+	for {
+		_, err = stream.Recv()
+		if err != nil {
+			break
+		}
+		count++
+	}
+
+	// The end of the stream is not reported as an error
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 5, count)
+}
+
+func TestGRPCHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	client := healthpb.NewHealthClient(grpcConn)
+
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	resp, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: grpcAPI.ToldataServiceName()})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "cdl.toldatatest.NoService"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCHealthWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := healthpb.NewHealthClient(grpcConn)
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "cdl.toldatatest.TestService"})
+	assert.Equal(t, nil, err)
+
+	resp, err := stream.Recv()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
+
+type testHealthChecker struct {
+	calls int
+	err   error
+}
+
+func (c *testHealthChecker) ToldataHealthCheck(ctx context.Context, req *toldata.Empty) (*toldata.ToldataHealthCheckInfo, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &toldata.ToldataHealthCheckInfo{}, nil
+}
+
+func TestHealthServer(t *testing.T) {
+	ctx := context.Background()
+	healthy := &testHealthChecker{}
+	failing := &testHealthChecker{err: errors.New("down")}
+
+	health := toldata.NewHealthServer(toldata.HealthOptions{CacheDuration: time.Hour})
+	health.AddService("a.Healthy", healthy)
+
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: "a.Healthy"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	// The result is cached
+	_, err = health.Check(ctx, &healthpb.HealthCheckRequest{Service: "a.Healthy"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, healthy.calls)
+
+	health.AddService("a.Failing", failing)
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{Service: "a.Failing"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	// The gateway is only serving when all of its services are
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	health.Shutdown()
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{Service: "a.Healthy"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	health.Resume()
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{Service: "a.Healthy"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
+
+func reflectionRequest(t *testing.T, req *rpb.ServerReflectionRequest) *rpb.ServerReflectionResponse {
+	stream, err := rpb.NewServerReflectionClient(grpcConn).ServerReflectionInfo(context.Background())
+	assert.Equal(t, nil, err)
+	defer stream.CloseSend()
+
+	err = stream.Send(req)
+	assert.Equal(t, nil, err)
+
+	resp, err := stream.Recv()
+	assert.Equal(t, nil, err)
+	return resp
+}
+
+func reflectionFileNames(t *testing.T, resp *rpb.ServerReflectionResponse) []string {
+	names := []string{}
+	for _, data := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		fd := &descriptor.FileDescriptorProto{}
+		err := proto.Unmarshal(data, fd)
+		assert.Equal(t, nil, err)
+		names = append(names, fd.GetName())
+	}
+	return names
+}
+
+func TestGRPCReflection(t *testing.T) {
+	resp := reflectionRequest(t, &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	})
+	services := []string{}
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		services = append(services, svc.Name)
+	}
+	assert.Contains(t, services, "cdl.toldatatest.TestService")
+	assert.Contains(t, services, "grpc.health.v1.Health")
+	assert.Contains(t, services, "grpc.reflection.v1alpha.ServerReflection")
+
+	for _, symbol := range []string{"cdl.toldatatest.TestService", "cdl.toldatatest.TestService.GetTestA", "cdl.toldatatest.TestARequest"} {
+		resp = reflectionRequest(t, &rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+		})
+		names := reflectionFileNames(t, resp)
+		assert.Equal(t, "toldata_test.proto", names[0], symbol)
+		// Dependencies are resolved from the gogo registry as well
+		assert.Contains(t, names, "github.com/citradigital/toldata/toldata.proto", symbol)
+	}
+
+	resp = reflectionRequest(t, &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "cdl.toldatatest.NoSuchThing"},
+	})
+	assert.Equal(t, int32(codes.NotFound), resp.GetErrorResponse().GetErrorCode())
+
+	resp = reflectionRequest(t, &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: "toldata_test.proto"},
+	})
+	assert.Equal(t, "toldata_test.proto", reflectionFileNames(t, resp)[0])
+}
+
+func TestGRPCEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	created := subscribeEvents(t, ctx, toldata.BroadcastDelivery, 1)
+
+	api, err := NewEventServiceGRPC(ctx, toldata.ServiceConfiguration{URL: natsURL}, toldata.GRPCOptions{})
+	assert.Equal(t, nil, err)
+	defer api.Close()
+	grpcCtx := peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 5000}})
+	_, err = api.Created(grpcCtx, &TestARequest{Input: "grpc"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"a:grpc:grpc"}, receivedEvents(created))
+}
+
+func TestGRPCInternal(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	api, err := NewOptionsServiceGRPC(ctx, toldata.ServiceConfiguration{URL: natsURL}, toldata.GRPCOptions{})
+	assert.Equal(t, nil, err)
+	defer api.Close()
+	_, err = api.Hidden(ctx, &TestARequest{Input: "grpc"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestGRPCUnimplemented(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+	_, err = NewLegacyServiceToldataServer(bus, &partialLegacyService{}).SubscribeLegacyService()
+	assert.Equal(t, nil, err)
+
+	api, err := NewLegacyServiceGRPC(ctx, toldata.ServiceConfiguration{URL: natsURL}, toldata.GRPCOptions{})
+	assert.Equal(t, nil, err)
+	defer api.Close()
+	_, err = api.Echo(ctx, &TestARequest{Input: "grpc"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
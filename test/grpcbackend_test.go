@@ -0,0 +1,145 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+const legacyAddr = "localhost:21002"
+
+// legacyService is a plain gRPC server which knows nothing about toldata
+type legacyService struct{}
+
+func (s *legacyService) Echo(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	if req.Input == "not-found" {
+		return nil, status.Error(codes.NotFound, "legacy-not-found")
+	}
+	return &TestAResponse{Output: "legacy:" + req.Input}, nil
+}
+
+func (s *legacyService) Sum(stream LegacyService_SumServer) error {
+	var sum int64
+	for {
+		data, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&FeedDataResponse{Sum: sum})
+		}
+		if err != nil {
+			return err
+		}
+		if data.Data < 0 {
+			return status.Error(codes.InvalidArgument, "negative")
+		}
+		sum = sum + data.Data
+	}
+}
+
+func (s *legacyService) Count(req *StreamDataRequest, stream LegacyService_CountServer) error {
+	for i := int64(1); i <= req.Id; i++ {
+		err := stream.Send(&StreamDataResponse{Data: i})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestGRPCBackend(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lis, err := net.Listen("tcp", legacyAddr)
+	assert.Equal(t, nil, err)
+	legacyServer := grpc.NewServer()
+	RegisterLegacyServiceServer(legacyServer, &legacyService{})
+	go legacyServer.Serve(lis)
+	defer legacyServer.Stop()
+
+	conn, err := grpc.Dial(legacyAddr, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(time.Second))
+	assert.Equal(t, nil, err)
+	defer conn.Close()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+
+	server := NewLegacyServiceToldataServer(bus, NewLegacyServiceGRPCBackend(conn))
+	done, err := server.SubscribeLegacyService()
+	assert.Equal(t, nil, err)
+
+	svc := NewLegacyServiceToldataClient(bus)
+
+	_, err = svc.ToldataHealthCheck(ctx, &toldata.Empty{})
+	assert.Equal(t, nil, err)
+
+	resp, err := svc.Echo(ctx, &TestARequest{Input: "hello"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "legacy:hello", resp.Output)
+
+	// The status of the gRPC error is carried over the bus
+	_, err = svc.Echo(ctx, &TestARequest{Input: "not-found"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "legacy-not-found", status.Convert(err).Message())
+
+	sumStream, err := svc.Sum(ctx)
+	assert.Equal(t, nil, err)
+	for i := 0; i < 10; i++ {
+		err = sumStream.Send(&FeedDataRequest{Data: int64(i)})
+		assert.Equal(t, nil, err)
+	}
+	sum, err := sumStream.Done()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(45), sum.Sum)
+
+	sumStream, err = svc.Sum(ctx)
+	assert.Equal(t, nil, err)
+	_ = sumStream.Send(&FeedDataRequest{Data: -1})
+	_, err = sumStream.Done()
+	assert.Equal(t, "negative", err.Error())
+
+	countStream, err := svc.Count(ctx, &StreamDataRequest{Id: 5})
+	assert.Equal(t, nil, err)
+	var total int64
+	count := 0
+	for {
+		data, err := countStream.Receive()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		total = total + data.Data
+		count++
+	}
+	assert.Equal(t, 5, count)
+	assert.Equal(t, int64(15), total)
+
+	// A failing backend is reported by the health check
+	conn.Close()
+	_, err = svc.ToldataHealthCheck(ctx, &toldata.Empty{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonCase struct {
+	name     string
+	options  toldata.JSONOptions
+	message  *JSONCorpus
+	expected string
+}
+
+var jsonCases = []jsonCase{
+	{
+		name:     "json_name is honoured",
+		message:  &JSONCorpus{Renamed: "x"},
+		expected: `{"custom-name":"x"}`,
+	},
+	{
+		name:     "field names become lowerCamelCase",
+		message:  &JSONCorpus{SnakeCaseField: "x"},
+		expected: `{"snakeCaseField":"x"}`,
+	},
+	{
+		name:     "64 bit integers are strings",
+		message:  &JSONCorpus{BigNumber: 9007199254740993, BigUnsigned: 18446744073709551615},
+		expected: `{"bigNumber":"9007199254740993","bigUnsigned":"18446744073709551615"}`,
+	},
+	{
+		name:     "32 bit integers, bools and doubles are numbers",
+		message:  &JSONCorpus{SmallNumber: 7, Flag: true, Ratio: 0.5},
+		expected: `{"smallNumber":7,"flag":true,"ratio":0.5}`,
+	},
+	{
+		name:     "enums are names",
+		message:  &JSONCorpus{Kind: JSONCorpusKind_KIND_BETA},
+		expected: `{"kind":"KIND_BETA"}`,
+	},
+	{
+		name:     "bytes are base64",
+		message:  &JSONCorpus{Payload: []byte("toldata")},
+		expected: `{"payload":"dG9sZGF0YQ=="}`,
+	},
+	{
+		name:     "repeated and map fields",
+		message:  &JSONCorpus{Tags: []string{"a", "b"}, Counters: map[string]int32{"a": 1}},
+		expected: `{"tags":["a","b"],"counters":{"a":1}}`,
+	},
+	{
+		name:     "oneof renders only the set member",
+		message:  &JSONCorpus{Choice: &JSONCorpus_ChoiceNumber{ChoiceNumber: 42}},
+		expected: `{"choiceNumber":"42"}`,
+	},
+	{
+		name:     "timestamp is RFC 3339",
+		message:  &JSONCorpus{CreatedAt: &Timestamp{Seconds: 1569895384, Nanos: 500000000}},
+		expected: `{"createdAt":"2019-10-01T02:03:04.500Z"}`,
+	},
+	{
+		name:     "duration is seconds with suffix",
+		message:  &JSONCorpus{Elapsed: durationProto(1500 * time.Millisecond)},
+		expected: `{"elapsed":"1.500s"}`,
+	},
+	{
+		name: "struct is a plain object",
+		message: &JSONCorpus{Attributes: &Struct{Fields: map[string]*Value{
+			"a": {Kind: &Value_StringValue{StringValue: "b"}},
+			"n": {Kind: &Value_NumberValue{NumberValue: 1}},
+		}}},
+		expected: `{"attributes":{"a":"b","n":1}}`,
+	},
+	{
+		name:     "wrappers are their primitive value",
+		message:  &JSONCorpus{Nickname: &StringValue{Value: "nick"}, OptionalCount: &Int64Value{Value: 5}},
+		expected: `{"nickname":"nick","optionalCount":"5"}`,
+	},
+	{
+		name:     "nested messages",
+		message:  &JSONCorpus{Nested: &TestARequest{Input: "x", Id: 3}},
+		expected: `{"nested":{"input":"x","id":"3"}}`,
+	},
+	{
+		name:     "original names",
+		options:  toldata.JSONOptions{OrigName: true},
+		message:  &JSONCorpus{Renamed: "x", SnakeCaseField: "y"},
+		expected: `{"renamed":"x","snake_case_field":"y"}`,
+	},
+	{
+		name:     "enums as numbers",
+		options:  toldata.JSONOptions{EnumsAsInts: true},
+		message:  &JSONCorpus{Kind: JSONCorpusKind_KIND_BETA},
+		expected: `{"kind":2}`,
+	},
+	{
+		name:     "legacy mapping",
+		options:  toldata.JSONOptions{Legacy: true},
+		message:  &JSONCorpus{Renamed: "x", SnakeCaseField: "y", BigNumber: 9007199254740993, Kind: JSONCorpusKind_KIND_BETA},
+		expected: `{"renamed":"x","snake_case_field":"y","big_number":9007199254740993,"kind":2,"Choice":null}`,
+	},
+	{
+		name:    "emit defaults",
+		options: toldata.JSONOptions{EmitDefaults: true},
+		message: &JSONCorpus{Payload: []byte{}},
+		expected: `{"custom-name":"","snakeCaseField":"","bigNumber":"0","bigUnsigned":"0",` +
+			`"smallNumber":0,"flag":false,"ratio":0,"kind":"KIND_UNSPECIFIED","payload":"",` +
+			`"tags":[],"counters":{},"createdAt":null,"elapsed":null,"attributes":null,` +
+			`"nickname":null,"optionalCount":null,"nested":null}`,
+	},
+}
+
+func TestJSONMapping(t *testing.T) {
+	for _, c := range jsonCases {
+		buf := bytes.NewBuffer(nil)
+		err := c.options.Marshal(buf, c.message)
+		assert.Equal(t, nil, err, c.name)
+		assert.JSONEq(t, c.expected, buf.String(), c.name)
+
+		if c.options.EmitDefaults {
+			continue
+		}
+
+		// Every rendering must be accepted back and yield the same message
+		var decoded JSONCorpus
+		err = c.options.Unmarshal(strings.NewReader(buf.String()), &decoded)
+		assert.Equal(t, nil, err, c.name)
+		assert.Equal(t, c.message.String(), decoded.String(), c.name)
+	}
+}
+
+func TestJSONUnmarshalAcceptsBothNames(t *testing.T) {
+	var opts toldata.JSONOptions
+
+	var camel JSONCorpus
+	err := opts.Unmarshal(strings.NewReader(`{"snakeCaseField":"x","bigNumber":12}`), &camel)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "x", camel.SnakeCaseField)
+	assert.Equal(t, int64(12), camel.BigNumber)
+
+	var orig JSONCorpus
+	err = opts.Unmarshal(strings.NewReader(`{"snake_case_field":"x","big_number":"12","kind":2}`), &orig)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "x", orig.SnakeCaseField)
+	assert.Equal(t, int64(12), orig.BigNumber)
+	assert.Equal(t, JSONCorpusKind_KIND_BETA, orig.Kind)
+}
+
+func TestJSONUnknownFields(t *testing.T) {
+	payload := `{"input":"x","notAField":1}`
+
+	var strict TestARequest
+	err := toldata.JSONOptions{}.Unmarshal(strings.NewReader(payload), &strict)
+	assert.NotEqual(t, nil, err)
+
+	var lenient TestARequest
+	err = toldata.JSONOptions{DiscardUnknown: true}.Unmarshal(strings.NewReader(payload), &lenient)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "x", lenient.Input)
+
+	var legacy TestARequest
+	err = toldata.JSONOptions{Legacy: true}.Unmarshal(strings.NewReader(payload), &legacy)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "x", legacy.Input)
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// countAll reads a StreamData stream through the client interface
+func countAll(stream TestService_StreamDataToldataClient) ([]int64, error) {
+	var result []int64
+	for {
+		resp, err := stream.Receive()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		result = append(result, resp.Data)
+	}
+}
+
+func TestClientMock(t *testing.T) {
+	ctx := context.Background()
+	mock := &TestServiceToldataClientMock{}
+	var svc TestServiceToldataClientInterface = mock
+
+	t.Run("Unary", func(t *testing.T) {
+		_, err := svc.GetTestA(ctx, &TestARequest{Input: "none"})
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+		mock.ReturnGetTestA(&TestAResponse{Output: "first"}, nil)
+		mock.ReturnGetTestA(nil, status.Error(codes.NotFound, "second"))
+		resp, err := svc.GetTestA(ctx, &TestARequest{Input: "a"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "first", resp.Output)
+		for i := 0; i < 2; i++ {
+			_, err = svc.GetTestA(ctx, &TestARequest{Input: "b"})
+			assert.Equal(t, codes.NotFound, status.Code(err))
+		}
+
+		calls := mock.CallsTo("GetTestA")
+		assert.Equal(t, 4, len(calls))
+		assert.Equal(t, "a", calls[1].Request.(*TestARequest).Input)
+
+		mock.GetTestABFunc = func(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+			return &TestAResponse{Output: "func:" + req.Input}, nil
+		}
+		resp, err = svc.GetTestAB(ctx, &TestARequest{Input: "c"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "func:c", resp.Output)
+	})
+
+	t.Run("Streams", func(t *testing.T) {
+		mock.ReturnStreamData([]*StreamDataResponse{{Data: 3}, {Data: 2}, {Data: 1}}, nil)
+		stream, err := svc.StreamData(ctx, &StreamDataRequest{Id: 1})
+		assert.Equal(t, nil, err)
+		values, err := countAll(stream)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []int64{3, 2, 1}, values)
+
+		mock.ReturnStreamData([]*StreamDataResponse{{Data: 4}}, errors.New("broken"))
+		stream, err = svc.StreamData(ctx, &StreamDataRequest{Id: 2})
+		assert.Equal(t, nil, err)
+		values, err = countAll(stream)
+		assert.NotEqual(t, nil, err)
+		assert.Equal(t, []int64{4}, values)
+
+		mock.ReturnFeedData(&FeedDataResponse{Sum: 42}, nil)
+		feed, err := svc.FeedData(ctx)
+		assert.Equal(t, nil, err)
+		for i := int64(1); i <= 3; i++ {
+			err = feed.Send(&FeedDataRequest{Data: i})
+			assert.Equal(t, nil, err)
+		}
+		resp, err := feed.Done()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, int64(42), resp.Sum)
+
+		calls := mock.CallsTo("FeedData")
+		assert.Equal(t, 1, len(calls))
+		assert.Equal(t, 3, len(calls[0].Sent))
+		assert.Equal(t, int64(3), calls[0].Sent[2].(*FeedDataRequest).Data)
+	})
+
+	t.Run("Gateway", func(t *testing.T) {
+		mock.Reset()
+		mock.ReturnGetTestA(&TestAResponse{Output: "mocked"}, nil)
+
+		api := &TestServiceREST{Context: ctx, Bus: &toldata.Bus{}, Service: mock}
+		server := httptest.NewServer(api.Handler())
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/api/test/cdl.toldatatest/TestService/GetTestA", toldata.ContentTypeJSON, bytes.NewBufferString(`{"input": "rest"}`))
+		assert.Equal(t, nil, err)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.JSONEq(t, `{"output": "mocked"}`, string(body))
+		assert.Equal(t, "rest", mock.Calls()[0].Request.(*TestARequest).Input)
+	})
+}
+
+func TestServerMock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+
+	mock := &LegacyServiceToldataMock{}
+	server := NewLegacyServiceToldataServer(bus, mock)
+	_, err = server.SubscribeLegacyService()
+	assert.Equal(t, nil, err)
+	svc := NewLegacyServiceToldataClient(bus)
+
+	_, err = svc.ToldataHealthCheck(ctx, &Empty{})
+	assert.Equal(t, nil, err)
+
+	mock.ReturnEcho(&TestAResponse{Output: "scripted"}, nil)
+	resp, err := svc.Echo(ctx, &TestARequest{Input: "bus"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "scripted", resp.Output)
+
+	mock.ReturnCount([]*StreamDataResponse{{Data: 1}, {Data: 2}}, nil)
+	count, err := svc.Count(ctx, &StreamDataRequest{Id: 2})
+	assert.Equal(t, nil, err)
+	var values []int64
+	for {
+		resp, err := count.Receive()
+		if err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		values = append(values, resp.Data)
+	}
+	assert.Equal(t, []int64{1, 2}, values)
+
+	mock.ReturnSum(&FeedDataResponse{Sum: 6}, nil)
+	sum, err := svc.Sum(ctx)
+	assert.Equal(t, nil, err)
+	for i := int64(1); i <= 3; i++ {
+		err = sum.Send(&FeedDataRequest{Data: i})
+		assert.Equal(t, nil, err)
+	}
+	total, err := sum.Done()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(6), total.Sum)
+
+	var methods []string
+	for _, call := range mock.Calls() {
+		methods = append(methods, call.Method)
+	}
+	assert.Equal(t, []string{"ToldataHealthCheck", "Echo", "Count", "Sum"}, methods)
+	assert.Equal(t, "bus", mock.CallsTo("Echo")[0].Request.(*TestARequest).Input)
+	assert.Equal(t, 3, len(mock.CallsTo("Sum")[0].Sent))
+}
@@ -0,0 +1,220 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/citradigital/toldata/gateway"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+type optionsService struct {
+	notified chan string
+}
+
+func (s *optionsService) ToldataHealthCheck(ctx context.Context, req *Empty) (*ToldataHealthCheckInfo, error) {
+	return &ToldataHealthCheckInfo{}, nil
+}
+
+func (s *optionsService) Slow(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	if req.Input == "slow" {
+		time.Sleep(time.Second)
+	}
+	return &TestAResponse{Output: "slow:" + req.Input}, nil
+}
+
+func (s *optionsService) Retry(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	return &TestAResponse{Output: "retry:" + req.Input}, nil
+}
+
+func (s *optionsService) Notify(ctx context.Context, req *TestARequest) (*Empty, error) {
+	s.notified <- req.Input
+	return &Empty{}, nil
+}
+
+func (s *optionsService) Hidden(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	return &TestAResponse{Output: "hidden:" + req.Input}, nil
+}
+
+func TestServiceOptions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, Retries: 20, RetryDelay: 50 * time.Millisecond})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+
+	svc := NewOptionsServiceToldataClient(bus)
+	impl := &optionsService{notified: make(chan string, 1)}
+
+	t.Run("Idempotent", func(t *testing.T) {
+		// Methods which are not idempotent are not retried
+		_, err := svc.Slow(ctx, &TestARequest{Input: "early"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+
+		// Retried until the server below subscribes
+		result := make(chan error)
+		go func() {
+			resp, err := svc.Retry(ctx, &TestARequest{Input: "early"})
+			if err == nil {
+				assert.Equal(t, "retry:early", resp.Output)
+			}
+			result <- err
+		}()
+		time.Sleep(200 * time.Millisecond)
+
+		server := NewOptionsServiceToldataServer(bus, impl)
+		_, err = server.SubscribeOptionsService()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, nil, <-result)
+	})
+
+	t.Run("Subject", func(t *testing.T) {
+		raw, err := marshal(&TestARequest{Input: "raw"})
+		assert.Equal(t, nil, err)
+		respRaw, err := bus.Call(ctx, "tenant/cdl.toldatatest/OptionsService/Retry", raw)
+		assert.Equal(t, nil, err)
+		resp := &TestAResponse{}
+		err = unmarshal(respRaw, resp)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "retry:raw", resp.Output)
+
+		_, err = svc.ToldataHealthCheck(ctx, &Empty{})
+		assert.Equal(t, nil, err)
+	})
+
+	t.Run("DefaultTimeout", func(t *testing.T) {
+		resp, err := svc.Slow(ctx, &TestARequest{Input: "fast"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "slow:fast", resp.Output)
+
+		_, err = svc.Slow(ctx, &TestARequest{Input: "slow"})
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+		// A deadline given by the caller is kept
+		longCtx, longCancel := context.WithTimeout(ctx, 2*time.Second)
+		defer longCancel()
+		_, err = svc.Slow(longCtx, &TestARequest{Input: "slow"})
+		assert.Equal(t, nil, err)
+	})
+
+	t.Run("FireAndForget", func(t *testing.T) {
+		_, err := svc.Notify(ctx, &TestARequest{Input: "event"})
+		assert.Equal(t, nil, err)
+		select {
+		case input := <-impl.notified:
+			assert.Equal(t, "event", input)
+		case <-time.After(time.Second):
+			t.Fatal("the notification was not delivered")
+		}
+	})
+
+	t.Run("Internal", func(t *testing.T) {
+		resp, err := svc.Hidden(ctx, &TestARequest{Input: "bus"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "hidden:bus", resp.Output)
+
+		rest, err := NewOptionsServiceREST(ctx, toldata.ServiceConfiguration{URL: natsURL}, toldata.RESTOptions{})
+		assert.Equal(t, nil, err)
+		var paths []string
+		for _, route := range rest.Routes() {
+			paths = append(paths, route.Path)
+		}
+		assert.Equal(t, []string{
+			"/api/options/cdl.toldatatest/OptionsService/Slow",
+			"/api/options/cdl.toldatatest/OptionsService/Retry",
+			"/api/options/cdl.toldatatest/OptionsService/Notify",
+		}, paths)
+	})
+
+	t.Run("Gateway", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "toldata-options")
+		assert.Equal(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		descriptors, err := gateway.LoadDescriptors(writeDescriptorSet(t, dir))
+		assert.Equal(t, nil, err)
+		service, err := descriptors.FindService("cdl.toldatatest.OptionsService")
+		assert.Equal(t, nil, err)
+
+		methods := service.Methods()
+		assert.Equal(t, "tenant/cdl.toldatatest/OptionsService/Slow", gateway.Subject(methods.ByName("Slow")))
+		assert.Equal(t, 200*time.Millisecond, gateway.DefaultTimeout(methods.ByName("Slow")))
+		assert.Equal(t, "/api/options", gateway.RESTMount(service))
+		assert.Equal(t, true, gateway.Internal(methods.ByName("Hidden")))
+		assert.Equal(t, false, gateway.Internal(methods.ByName("Retry")))
+		assert.Equal(t, true, gateway.Idempotent(methods.ByName("Retry")))
+		assert.Equal(t, true, gateway.FireAndForget(methods.ByName("Notify")))
+		assert.Equal(t, false, gateway.FireAndForget(methods.ByName("Retry")))
+
+		g, err := gateway.New(ctx, gateway.Config{
+			Descriptors: []string{writeDescriptorSet(t, dir)},
+			Services:    []string{"cdl.toldatatest.OptionsService"},
+			NATS: gateway.NATSConfig{
+				URL:        natsURL,
+				Retries:    20,
+				RetryDelay: gateway.Duration(50 * time.Millisecond),
+				Subjects:   gateway.SubjectsConfig{Environment: "late"},
+			},
+		})
+		assert.Equal(t, nil, err)
+		defer g.Close()
+		httpServer := httptest.NewServer(g.RESTHandler())
+		defer httpServer.Close()
+
+		// The gateway retries the idempotent methods until the server below subscribes
+		result := make(chan string)
+		go func() {
+			resp, err := http.Post(httpServer.URL+"/api/options/cdl.toldatatest/OptionsService/Retry", toldata.ContentTypeJSON, bytes.NewBufferString(`{"input": "gateway"}`))
+			if !assert.Equal(t, nil, err) {
+				result <- ""
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+			result <- string(body)
+		}()
+		time.Sleep(200 * time.Millisecond)
+
+		lateBus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, Subjects: toldata.SubjectScheme{Environment: "late"}})
+		assert.Equal(t, nil, err)
+		defer lateBus.Close()
+		_, err = NewOptionsServiceToldataServer(lateBus, impl).SubscribeOptionsService()
+		assert.Equal(t, nil, err)
+		assert.JSONEq(t, `{"output": "retry:gateway"}`, <-result)
+
+		// The fire_and_forget methods are published
+		resp, err := http.Post(httpServer.URL+"/api/options/cdl.toldatatest/OptionsService/Notify", toldata.ContentTypeJSON, bytes.NewBufferString(`{"input": "gateway"}`))
+		assert.Equal(t, nil, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		select {
+		case input := <-impl.notified:
+			assert.Equal(t, "gateway", input)
+		case <-time.After(time.Second):
+			t.Fatal("the notification was not delivered")
+		}
+	})
+}
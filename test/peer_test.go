@@ -0,0 +1,82 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	proxies, err := toldata.NewTrustedProxies("10.0.0.0/8", "192.0.2.1", "fd00::/8")
+	assert.Equal(t, nil, err)
+
+	cases := []struct {
+		name          string
+		remoteAddr    string
+		forwarded     []string
+		xForwardedFor []string
+		expected      string
+	}{
+		{"direct IPv4", "198.51.100.1:1234", nil, nil, "198.51.100.1"},
+		{"direct IPv6", "[2001:db8::1]:1234", nil, nil, "2001:db8::1"},
+		{"untrusted remote is not allowed to forward", "198.51.100.1:1234", nil, []string{"203.0.113.5"}, "198.51.100.1"},
+		{"trusted remote", "10.1.1.1:1234", nil, []string{"203.0.113.5"}, "203.0.113.5"},
+		{"chain of trusted proxies", "10.1.1.1:1234", nil, []string{"203.0.113.5, 192.0.2.1", "10.2.2.2"}, "203.0.113.5"},
+		{"spoofed entries before the last untrusted hop", "10.1.1.1:1234", nil, []string{"1.1.1.1, 203.0.113.5, 10.2.2.2"}, "203.0.113.5"},
+		{"only trusted hops", "10.1.1.1:1234", nil, []string{"10.2.2.2"}, "10.2.2.2"},
+		{"forwarded takes precedence", "[fd00::1]:1234", []string{"for=203.0.113.7;proto=https, for=\"[fd00::2]:80\""}, []string{"203.0.113.5"}, "203.0.113.7"},
+		{"forwarded IPv6 with port", "10.1.1.1:1234", []string{"for=\"[2001:db8:cafe::17]:4711\""}, nil, "2001:db8:cafe::17"},
+		{"invalid forwarded entry stops the walk", "10.1.1.1:1234", nil, []string{"203.0.113.5, unknown"}, "10.1.1.1"},
+	}
+
+	for _, c := range cases {
+		p := proxies.ResolvePeer(c.remoteAddr, c.forwarded, c.xForwardedFor)
+		assert.Equal(t, c.expected, p.Addr.String(), c.name)
+	}
+
+	_, err = toldata.NewTrustedProxies("not-an-ip")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestPeerPropagation(t *testing.T) {
+	ctx := context.Background()
+	client, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer client.Close()
+
+	svc := NewTestServiceToldataClient(client)
+
+	callCtx := toldata.NewPeerContext(ctx, &toldata.Peer{
+		Addr:      &net.IPAddr{IP: net.ParseIP("2001:db8::42")},
+		UserAgent: "agent/1.0",
+		Gateway:   "gw-1",
+		Protocol:  "http",
+	})
+	resp, err := svc.GetTestGetIP(callCtx, &Empty{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "2001:db8::42", resp.Ip)
+	assert.Equal(t, "agent/1.0", resp.UserAgent)
+	assert.Equal(t, "gw-1", resp.Gateway)
+	assert.Equal(t, "http", resp.Protocol)
+
+	// Without caller information the server does not make up a peer
+	_, err = svc.GetTestGetIP(ctx, &Empty{})
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, "no-peer", err.Error())
+}
@@ -2,10 +2,13 @@ package test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
-	"encoding/json"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +18,12 @@ import (
 
 const serverAddrREST = "localhost:21002"
 
+const restAllowedOrigin = "https://allowed.example"
+const restMaxBodySize = 1024
+
+var restJSON toldata.JSONOptions
+var restAPI *TestServiceREST
+
 func startRESTTestServer(s *http.Server) {
 
 	log.Println("Starting REST server...")
@@ -23,11 +32,26 @@ func startRESTTestServer(s *http.Server) {
 
 func TestRESTInit(t *testing.T) {
 	ctx := context.Background()
-	api, err := NewTestServiceREST(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	proxies, err := toldata.NewTrustedProxies("127.0.0.1", "::1")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	api, err := NewTestServiceREST(ctx, toldata.ServiceConfiguration{URL: natsURL, ID: "rest-gateway"}, toldata.RESTOptions{
+		TrustedProxies: proxies,
+		JSON:           restJSON,
+		MaxBodySize:    restMaxBodySize,
+		AllowedOrigins: []string{restAllowedOrigin},
+		AllowedHeaders: []string{"Authorization"},
+		CORSMaxAge:     time.Minute,
+		Gzip:           true,
+	})
 	if err != nil {
 		log.Fatalln("Failed to create Toldata service")
 	}
 
+	restAPI = api
+
 	mux := http.NewServeMux()
 	api.InstallTestServiceMux(mux)
 	s := &http.Server{
@@ -50,11 +74,12 @@ func TestREST1(t *testing.T) {
 		Input: "REST",
 	}
 
-	jsonPayload, err := json.Marshal(req)
+	jsonPayload := bytes.NewBuffer(nil)
+	err := restJSON.Marshal(jsonPayload, req)
 	assert.Equal(t, nil, err)
 
 	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestA"
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
+	httpReq, err := http.NewRequest("POST", url, jsonPayload)
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
@@ -63,9 +88,8 @@ func TestREST1(t *testing.T) {
 	defer httpResp.Body.Close()
 
 	var resp TestAResponse
-	err = json.NewDecoder(httpResp.Body).Decode(&resp)
+	err = restJSON.Unmarshal(httpResp.Body, &resp)
 	assert.Equal(t, nil, err)
-	assert.NotEqual(t, nil, resp)
 	assert.Equal(t, "OKREST", resp.Output)
 }
 
@@ -75,11 +99,12 @@ func TestREST2(t *testing.T) {
 		Id:    199,
 	}
 
-	jsonPayload, err := json.Marshal(req)
+	jsonPayload := bytes.NewBuffer(nil)
+	err := restJSON.Marshal(jsonPayload, req)
 	assert.Equal(t, nil, err)
 
 	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestAB"
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
+	httpReq, err := http.NewRequest("POST", url, jsonPayload)
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
@@ -88,9 +113,8 @@ func TestREST2(t *testing.T) {
 	defer httpResp.Body.Close()
 
 	var resp TestAResponse
-	err = json.NewDecoder(httpResp.Body).Decode(&resp)
+	err = restJSON.Unmarshal(httpResp.Body, &resp)
 	assert.Equal(t, nil, err)
-	assert.NotEqual(t, nil, resp)
 	assert.Equal(t, "ABREST", resp.Output)
 	assert.Equal(t, int64(199), resp.Id)
 }
@@ -101,11 +125,12 @@ func TestRESTError(t *testing.T) {
 		Id:    999,
 	}
 
-	jsonPayload, err := json.Marshal(req)
+	jsonPayload := bytes.NewBuffer(nil)
+	err := restJSON.Marshal(jsonPayload, req)
 	assert.Equal(t, nil, err)
 
 	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestAB"
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
+	httpReq, err := http.NewRequest("POST", url, jsonPayload)
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
@@ -115,10 +140,9 @@ func TestRESTError(t *testing.T) {
 
 	assert.Equal(t, 500, httpResp.StatusCode)
 
-	var errResp toldata.ErrorMessage
-	err = json.NewDecoder(httpResp.Body).Decode(&errResp)
+	var errResp ErrorMessage
+	err = restJSON.Unmarshal(httpResp.Body, &errResp)
 	assert.Equal(t, nil, err)
-	assert.NotEqual(t, nil, errResp)
 	assert.Equal(t, "test-error-1", errResp.ErrorMessage)
 }
 
@@ -133,9 +157,370 @@ func TestRESTGetIP(t *testing.T) {
 	defer httpResp.Body.Close()
 
 	var resp TestGetIPResponse
-	err = json.NewDecoder(httpResp.Body).Decode(&resp)
+	err = restJSON.Unmarshal(httpResp.Body, &resp)
 	assert.Equal(t, nil, err)
-	assert.NotEqual(t, nil, resp)
 	assert.NotEqual(t, "", resp.Ip)
 	log.Println("req ip: ", resp.Ip)
 }
+
+func TestRESTGetIPForwarded(t *testing.T) {
+	cases := []struct {
+		header   string
+		value    string
+		expected string
+	}{
+		{"X-Forwarded-For", "198.51.100.7", "198.51.100.7"},
+		{"X-Forwarded-For", "198.51.100.7, 10.1.2.3", "10.1.2.3"},
+		{"X-Forwarded-For", "198.51.100.7, 127.0.0.1", "198.51.100.7"},
+		{"Forwarded", "for=\"[2001:db8:cafe::17]:4711\";proto=https", "2001:db8:cafe::17"},
+	}
+
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestGetIP"
+	for _, c := range cases {
+		httpReq, err := http.NewRequest("POST", url, bytes.NewBufferString("{}"))
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("User-Agent", "toldata-test")
+		httpReq.Header.Set(c.header, c.value)
+
+		httpResp, err := http.DefaultClient.Do(httpReq)
+		assert.Equal(t, nil, err)
+
+		var resp TestGetIPResponse
+		err = restJSON.Unmarshal(httpResp.Body, &resp)
+		httpResp.Body.Close()
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, c.expected, resp.Ip, c.value)
+		assert.Equal(t, "toldata-test", resp.UserAgent)
+		assert.Equal(t, "rest-gateway", resp.Gateway)
+		assert.Equal(t, "http", resp.Protocol)
+	}
+}
+
+func TestRESTJSONCorpus(t *testing.T) {
+	payload := `{"custom-name":"x","bigNumber":"9007199254740993","kind":"KIND_ALPHA",` +
+		`"choiceText":"t","createdAt":"2019-10-01T02:03:04Z","elapsed":"2s",` +
+		`"attributes":{"a":["b",true]},"nickname":"nick","optionalCount":"5"}`
+
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/EchoJSON"
+	httpResp, err := http.Post(url, "application/json", strings.NewReader(payload))
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, "application/json", httpResp.Header.Get("Content-Type"))
+
+	body, err := ioutil.ReadAll(httpResp.Body)
+	assert.Equal(t, nil, err)
+	assert.JSONEq(t, payload, string(body))
+}
+
+func TestRESTJSONRejectsUnknownFields(t *testing.T) {
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/EchoJSON"
+	httpResp, err := http.Post(url, "application/json", strings.NewReader(`{"notAField":1}`))
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+
+	var errResp ErrorMessage
+	err = restJSON.Unmarshal(httpResp.Body, &errResp)
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, "", errResp.ErrorMessage)
+}
+
+func TestRESTProtobuf(t *testing.T) {
+	payload, err := marshal(&TestARequest{Input: "PB", Id: 7})
+	assert.Equal(t, nil, err)
+
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestA"
+	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	httpReq.Header.Set("Content-Type", toldata.ContentTypeProtobuf)
+
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, toldata.ContentTypeProtobuf, httpResp.Header.Get("Content-Type"))
+
+	raw, err := ioutil.ReadAll(httpResp.Body)
+	assert.Equal(t, nil, err)
+
+	var resp TestAResponse
+	err = unmarshal(raw, &resp)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "OKPB", resp.Output)
+	assert.Equal(t, int64(7), resp.Id)
+}
+
+func TestRESTProtobufError(t *testing.T) {
+	payload, err := marshal(&TestARequest{Input: "123456"})
+	assert.Equal(t, nil, err)
+
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestA"
+	httpResp, err := http.Post(url, toldata.ContentTypeProtobuf, bytes.NewReader(payload))
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusInternalServerError, httpResp.StatusCode)
+	assert.Equal(t, toldata.ContentTypeProtobuf, httpResp.Header.Get("Content-Type"))
+
+	raw, err := ioutil.ReadAll(httpResp.Body)
+	assert.Equal(t, nil, err)
+
+	var errResp ErrorMessage
+	err = unmarshal(raw, &errResp)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "test-error-1", errResp.ErrorMessage)
+}
+
+func TestRESTProtobufToJSON(t *testing.T) {
+	payload, err := marshal(&TestARequest{Input: "PB"})
+	assert.Equal(t, nil, err)
+
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestA"
+	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	httpReq.Header.Set("Content-Type", toldata.ContentTypeProtobuf)
+	httpReq.Header.Set("Accept", "text/html;q=0.9, application/json;q=0.8")
+
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, toldata.ContentTypeJSON, httpResp.Header.Get("Content-Type"))
+
+	var resp TestAResponse
+	err = restJSON.Unmarshal(httpResp.Body, &resp)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "OKPB", resp.Output)
+}
+
+func TestRESTUnsupportedMediaType(t *testing.T) {
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestA"
+	httpResp, err := http.Post(url, "text/plain", strings.NewReader("input=x"))
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, httpResp.StatusCode)
+}
+
+func TestRESTNotAcceptable(t *testing.T) {
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestA"
+	httpReq, err := http.NewRequest("POST", url, strings.NewReader("{}"))
+	httpReq.Header.Set("Content-Type", toldata.ContentTypeJSON)
+	httpReq.Header.Set("Accept", "text/html, application/json;q=0")
+
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusNotAcceptable, httpResp.StatusCode)
+	assert.Equal(t, toldata.ContentTypeJSON, httpResp.Header.Get("Content-Type"))
+}
+
+func TestRESTBodyTooLarge(t *testing.T) {
+	payload := `{"input":"` + strings.Repeat("x", restMaxBodySize) + `"}`
+
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestA"
+	httpResp, err := http.Post(url, toldata.ContentTypeJSON, strings.NewReader(payload))
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, httpResp.StatusCode)
+}
+
+func TestRESTCORS(t *testing.T) {
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestA"
+
+	preflight, err := http.NewRequest("OPTIONS", url, nil)
+	preflight.Header.Set("Origin", restAllowedOrigin)
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+
+	httpResp, err := http.DefaultClient.Do(preflight)
+	assert.Equal(t, nil, err)
+	httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, httpResp.StatusCode)
+	assert.Equal(t, restAllowedOrigin, httpResp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "POST, OPTIONS", httpResp.Header.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Accept, Authorization", httpResp.Header.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "60", httpResp.Header.Get("Access-Control-Max-Age"))
+
+	httpReq, err := http.NewRequest("POST", url, strings.NewReader(`{"input":"CORS"}`))
+	httpReq.Header.Set("Origin", restAllowedOrigin)
+	httpResp, err = http.DefaultClient.Do(httpReq)
+	assert.Equal(t, nil, err)
+	httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, restAllowedOrigin, httpResp.Header.Get("Access-Control-Allow-Origin"))
+
+	httpReq, err = http.NewRequest("POST", url, strings.NewReader(`{"input":"CORS"}`))
+	httpReq.Header.Set("Origin", "https://denied.example")
+	httpResp, err = http.DefaultClient.Do(httpReq)
+	assert.Equal(t, nil, err)
+	httpResp.Body.Close()
+
+	assert.Equal(t, "", httpResp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestRESTGzip(t *testing.T) {
+	url := "http://" + serverAddrREST + "/api/test/cdl.toldatatest/TestService/GetTestA"
+	httpReq, err := http.NewRequest("POST", url, strings.NewReader(`{"input":"GZ"}`))
+	httpReq.Header.Set("Content-Type", toldata.ContentTypeJSON)
+	httpReq.Header.Set("Accept-Encoding", "gzip")
+
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	httpResp, err := client.Do(httpReq)
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, "gzip", httpResp.Header.Get("Content-Encoding"))
+
+	body, err := gzip.NewReader(httpResp.Body)
+	assert.Equal(t, nil, err)
+
+	var resp TestAResponse
+	err = restJSON.Unmarshal(body, &resp)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "OKGZ", resp.Output)
+}
+
+func TestRESTNegotiate(t *testing.T) {
+	var options toldata.RESTOptions
+
+	cases := []struct {
+		contentType string
+		accept      string
+		expected    string
+		err         error
+	}{
+		{"", "", toldata.ContentTypeJSON, nil},
+		{toldata.ContentTypeProtobuf, "", toldata.ContentTypeProtobuf, nil},
+		{toldata.ContentTypeJSON, "*/*", toldata.ContentTypeJSON, nil},
+		{toldata.ContentTypeProtobuf, "application/*", toldata.ContentTypeProtobuf, nil},
+		{toldata.ContentTypeJSON, "application/x-protobuf", toldata.ContentTypeProtobuf, nil},
+		{toldata.ContentTypeJSON, "application/json;q=0.5, application/x-protobuf;q=0.9", toldata.ContentTypeProtobuf, nil},
+		{toldata.ContentTypeJSON, "text/html", "", toldata.ErrNotAcceptable},
+		{toldata.ContentTypeJSON, "application/json;q=0", "", toldata.ErrNotAcceptable},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Content-Type", c.contentType)
+		r.Header.Set("Accept", c.accept)
+
+		contentType, err := options.Negotiate(r)
+		assert.Equal(t, c.err, err, c.accept)
+		assert.Equal(t, c.expected, contentType, c.accept)
+	}
+}
+
+func TestRESTRoutes(t *testing.T) {
+	routes := restAPI.Routes()
+
+	methods := make(map[string]toldata.RESTRoute)
+	for _, route := range routes {
+		assert.Equal(t, "cdl.toldatatest.TestService", route.Service)
+		assert.Equal(t, http.MethodPost, route.HTTPMethod)
+		assert.Equal(t, true, route.Handler != nil)
+		methods[route.Method] = route
+	}
+
+	// Streaming methods are not exposed over REST
+	assert.Equal(t, 5, len(routes))
+	_, ok := methods["StreamData"]
+	assert.Equal(t, false, ok)
+
+	route := methods["GetTestA"]
+	assert.Equal(t, "/api/test/cdl.toldatatest/TestService/GetTestA", route.Path)
+	assert.Equal(t, "/cdl.toldatatest.TestService/GetTestA", route.FullMethod())
+}
+
+func TestRESTRoutesCustomRouter(t *testing.T) {
+	var seen []string
+
+	// A router mounting each route with middleware keyed by the toldata method
+	router := http.NewServeMux()
+	for _, route := range restAPI.Routes() {
+		route := route
+		router.Handle("/custom"+route.Path, http.StripPrefix("/custom", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = append(seen, route.FullMethod())
+			if route.Method == "GetTestAB" {
+				http.Error(w, "denied", http.StatusForbidden)
+				return
+			}
+			route.Handler.ServeHTTP(w, r)
+		})))
+	}
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	httpResp, err := http.Post(server.URL+"/custom/api/test/cdl.toldatatest/TestService/GetTestA", toldata.ContentTypeJSON, strings.NewReader(`{"input":"ROUTE"}`))
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	var resp TestAResponse
+	err = restJSON.Unmarshal(httpResp.Body, &resp)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "OKROUTE", resp.Output)
+
+	httpResp, err = http.Post(server.URL+"/custom/api/test/cdl.toldatatest/TestService/GetTestAB", toldata.ContentTypeJSON, strings.NewReader(`{}`))
+	assert.Equal(t, nil, err)
+	httpResp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+
+	assert.Equal(t, []string{"/cdl.toldatatest.TestService/GetTestA", "/cdl.toldatatest.TestService/GetTestAB"}, seen)
+}
+
+func TestRESTHandler(t *testing.T) {
+	server := httptest.NewServer(restAPI.Handler())
+	defer server.Close()
+
+	httpResp, err := http.Post(server.URL+"/api/test/cdl.toldatatest/TestService/GetTestA", toldata.ContentTypeJSON, strings.NewReader(`{"input":"HANDLER"}`))
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+
+	var resp TestAResponse
+	err = restJSON.Unmarshal(httpResp.Body, &resp)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "OKHANDLER", resp.Output)
+}
+
+func TestRESTLegacyJSON(t *testing.T) {
+	api, err := NewTestServiceREST(context.Background(), toldata.ServiceConfiguration{URL: natsURL}, toldata.RESTOptions{
+		JSON: toldata.JSONOptions{Legacy: true},
+	})
+	assert.Equal(t, nil, err)
+	defer api.Bus.Close()
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	// The payload of the clients of the earlier gateways, with a field they
+	// do not know about
+	httpResp, err := http.Post(server.URL+"/api/test/cdl.toldatatest/TestService/GetTestAB", toldata.ContentTypeJSON, strings.NewReader(`{"input":"LEGACY","id":199,"extra":true}`))
+	assert.Equal(t, nil, err)
+	defer httpResp.Body.Close()
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	body, err := ioutil.ReadAll(httpResp.Body)
+	assert.Equal(t, nil, err)
+	assert.JSONEq(t, `{"output":"ABLEGACY","id":199}`, string(body))
+}
+
+func TestRESTRouteFromContext(t *testing.T) {
+	var found toldata.RESTRoute
+	route := toldata.NewRESTRoute("pkg.Service", "Method", "/pkg/Service/Method", toldata.RESTOptions{}, func(w http.ResponseWriter, r *http.Request) {
+		found, _ = toldata.RESTRouteFromContext(r.Context())
+	})
+
+	route.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/pkg/Service/Method", nil))
+
+	assert.Equal(t, "/pkg.Service/Method", found.FullMethod())
+	assert.Equal(t, "/pkg/Service/Method", found.Path)
+}
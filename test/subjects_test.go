@@ -0,0 +1,169 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// schemeService serves Echo and Count with the id of the server and counts
+// the calls of Echo
+type schemeService struct {
+	partialLegacyService
+	id    string
+	calls int32
+}
+
+func (s *schemeService) Echo(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return &TestAResponse{Output: s.id + ":" + req.Input}, nil
+}
+
+func TestSubjectScheme(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newBus := func(config toldata.ServiceConfiguration) *toldata.Bus {
+		config.URL = natsURL
+		bus, err := toldata.NewBus(ctx, config)
+		assert.Equal(t, nil, err)
+		t.Cleanup(bus.Close)
+		return bus
+	}
+	serve := func(config toldata.ServiceConfiguration, id string) *schemeService {
+		bus := newBus(config)
+		service := &schemeService{id: id}
+		server := NewLegacyServiceToldataServer(bus, service)
+		_, err := server.SubscribeLegacyService()
+		assert.Equal(t, nil, err)
+		bus.Connection.Flush()
+		return service
+	}
+
+	staging := toldata.SubjectScheme{Prefix: "acme", Environment: "staging", Separator: "."}
+	production := toldata.SubjectScheme{Prefix: "acme", Environment: "production", Separator: "."}
+	serve(toldata.ServiceConfiguration{Subjects: staging}, "staging")
+	serve(toldata.ServiceConfiguration{Subjects: production}, "production")
+
+	t.Run("Subjects", func(t *testing.T) {
+		assert.Equal(t, "cdl.toldatatest/LegacyService/Echo", toldata.SubjectScheme{}.Subject("cdl.toldatatest/LegacyService", "Echo"))
+		assert.Equal(t, "acme.staging.cdl.toldatatest.LegacyService.Echo", staging.Subject("cdl.toldatatest/LegacyService", "Echo"))
+		assert.Equal(t, "acme.staging.workers", staging.QueueGroup("workers"))
+		assert.Equal(t, "cdl_toldatatest_LegacyService", toldata.SubjectScheme{}.StreamName("cdl.toldatatest/LegacyService"))
+		assert.Equal(t, "acme_staging_cdl_toldatatest_LegacyService", staging.StreamName("cdl.toldatatest/LegacyService"))
+	})
+
+	t.Run("Environments", func(t *testing.T) {
+		bus := newBus(toldata.ServiceConfiguration{Subjects: staging})
+
+		// The calls of the environment are matched by a wildcard
+		calls, err := bus.Connection.SubscribeSync("acme.staging.>")
+		assert.Equal(t, nil, err)
+		defer calls.Unsubscribe()
+
+		svc := NewLegacyServiceToldataClient(bus)
+		resp, err := svc.Echo(ctx, &TestARequest{Input: "a"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "staging:a", resp.Output)
+		m, err := calls.NextMsg(time.Second)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "acme.staging.cdl.toldatatest.LegacyService.Echo", m.Subject)
+
+		_, err = svc.ToldataHealthCheck(ctx, &Empty{})
+		assert.Equal(t, nil, err)
+
+		count, err := svc.Count(ctx, &StreamDataRequest{Id: 2})
+		assert.Equal(t, nil, err)
+		data, err := count.Receive()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, int64(1), data.Data)
+
+		sum, err := svc.Sum(ctx)
+		assert.Equal(t, nil, err)
+		err = sum.Send(&FeedDataRequest{Data: 1})
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+		replies, err := svc.EchoAll(ctx, &TestARequest{Input: "all"}, toldata.GatherOptions{Timeout: 200 * time.Millisecond})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 1, len(replies))
+
+		svc = NewLegacyServiceToldataClient(newBus(toldata.ServiceConfiguration{Subjects: production}))
+		resp, err = svc.Echo(ctx, &TestARequest{Input: "b"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "production:b", resp.Output)
+
+		// No server uses the legacy scheme
+		svc = NewLegacyServiceToldataClient(newBus(toldata.ServiceConfiguration{}))
+		_, err = svc.Echo(ctx, &TestARequest{Input: "c"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("Migration", func(t *testing.T) {
+		// A server not migrated yet runs along the migrated one
+		migrated := toldata.SubjectScheme{Prefix: "migrated", Separator: "."}
+		legacyServer := serve(toldata.ServiceConfiguration{}, "legacy")
+		migratedServer := serve(toldata.ServiceConfiguration{Subjects: migrated, PreviousSubjects: &toldata.SubjectScheme{}}, "migrated")
+
+		for _, scheme := range []toldata.SubjectScheme{migrated, {}} {
+			svc := NewLegacyServiceToldataClient(newBus(toldata.ServiceConfiguration{Subjects: scheme}))
+			resp, err := svc.Echo(ctx, &TestARequest{Input: "m"})
+			assert.Equal(t, nil, err)
+			if scheme.Prefix == "" {
+				assert.Contains(t, []string{"legacy:m", "migrated:m"}, resp.Output)
+			} else {
+				assert.Equal(t, "migrated:m", resp.Output)
+			}
+
+			count, err := svc.Count(ctx, &StreamDataRequest{Id: 1})
+			assert.Equal(t, nil, err)
+			data, err := count.Receive()
+			assert.Equal(t, nil, err)
+			assert.Equal(t, int64(1), data.Data)
+		}
+
+		// Each call of the legacy clients is handled by one of the servers
+		svc := NewLegacyServiceToldataClient(newBus(toldata.ServiceConfiguration{}))
+		for i := 0; i < 20; i++ {
+			_, err := svc.Echo(ctx, &TestARequest{Input: "once"})
+			assert.Equal(t, nil, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, int32(22), atomic.LoadInt32(&legacyServer.calls)+atomic.LoadInt32(&migratedServer.calls))
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		custom := toldata.SubjectScheme{Format: func(service, method string) string {
+			return "custom." + method
+		}}
+		serve(toldata.ServiceConfiguration{Subjects: custom}, "custom")
+
+		bus := newBus(toldata.ServiceConfiguration{Subjects: custom})
+		svc := NewLegacyServiceToldataClient(bus)
+		resp, err := svc.Echo(ctx, &TestARequest{Input: "f"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "custom:f", resp.Output)
+
+		raw, err := bus.Connection.Request("custom.ToldataHealthCheck", nil, time.Second)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, byte(0), raw.Data[0])
+	})
+}
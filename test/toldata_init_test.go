@@ -7,13 +7,18 @@ import (
 	"testing"
 
 	"github.com/citradigital/toldata"
+	"github.com/citradigital/toldata/test/natstest"
 )
 
 var d *TestToldataService
 
 func TestMain(m *testing.M) {
-	natsURL = os.Getenv("NATS_URL")
 	log.SetFlags(log.Lshortfile | log.Lmicroseconds)
+	url, shutdown, err := natstest.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	natsURL = url
 	d = createTestService()
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -31,5 +36,7 @@ func TestMain(m *testing.M) {
 
 	cancel()
 	<-done
+	bus.Close()
+	shutdown()
 	os.Exit(code)
 }
@@ -25,25 +25,45 @@ import (
 
 	"github.com/citradigital/toldata"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 type TestToldataService struct {
 	Fixtures Fixtures
 }
 
-func (b *TestToldataService) ToldataHealthCheck(ctx context.Context, req *toldata.Empty) (*toldata.ToldataHealthCheckInfo, error) {
-	ret := &toldata.ToldataHealthCheckInfo{Data: ""}
+func (b *TestToldataService) ToldataHealthCheck(ctx context.Context, req *Empty) (*ToldataHealthCheckInfo, error) {
+	ret := &ToldataHealthCheckInfo{Data: ""}
 	return ret, nil
 }
 
-func (b *TestToldataService) TestEmpty(ctx context.Context, req *toldata.Empty) (*toldata.Empty, error) {
+func (b *TestToldataService) TestEmpty(ctx context.Context, req *Empty) (*Empty, error) {
 	return nil, nil
 }
 
+func (b *TestToldataService) EchoJSON(ctx context.Context, req *JSONCorpus) (*JSONCorpus, error) {
+	return req, nil
+}
+
 func (b *TestToldataService) GetTestA(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
-	if req.Input == "123456" {
+	switch req.Input {
+	case "123456":
 		return nil, errors.New("test-error-1")
+	case "not-found":
+		return nil, status.Error(codes.NotFound, "missing")
+	case "details":
+		st, err := status.New(codes.InvalidArgument, "bad-input").WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "input", Description: "invalid"},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, st.Err()
 	}
 
 	id := ctx.Value(string("BusID"))
@@ -75,11 +95,20 @@ func (b *TestToldataService) GetTestAB(ctx context.Context, req *TestARequest) (
 	return result, nil
 }
 
-func (b *TestToldataService) GetTestGetIP(ctx context.Context, req *toldata.Empty) (*TestGetIPResponse, error) {
-	pInfo, _ := peer.FromContext(ctx)
+func (b *TestToldataService) GetTestGetIP(ctx context.Context, req *Empty) (*TestGetIPResponse, error) {
+	pInfo, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("no-peer")
+	}
 	result := &TestGetIPResponse{
 		Ip: pInfo.Addr.String(),
 	}
+
+	if caller, ok := toldata.PeerFromContext(ctx); ok {
+		result.UserAgent = caller.UserAgent
+		result.Gateway = caller.Gateway
+		result.Protocol = caller.Protocol
+	}
 	return result, nil
 }
 
@@ -182,6 +211,27 @@ func TestError1(t *testing.T) {
 	assert.Equal(t, "test-error-1", err.Error())
 }
 
+func TestErrorCode(t *testing.T) {
+	ctx := context.Background()
+	client, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+
+	defer client.Close()
+
+	svc := NewTestServiceToldataClient(client)
+
+	_, err = svc.GetTestA(ctx, &TestARequest{Input: "not-found"})
+	assert.Equal(t, "missing", err.Error())
+	assert.Equal(t, codes.NotFound, err.(*toldata.Error).Code)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = svc.GetTestA(ctx, &TestARequest{Input: "123456"})
+	assert.Equal(t, codes.Unknown, status.Code(err))
+
+	_, err = svc.GetTestA(ctx, nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func testOK1(t *testing.T, title string) {
 	log.Println(title)
 
@@ -481,7 +531,7 @@ func TestServerStreamSad1(t *testing.T) {
 		// Wait for the data to be available from the stream
 		data, err := stream.Receive()
 		if count == 8 {
-			assert.Equal(t, errors.New("crash"), err)
+			assert.EqualError(t, err, "crash")
 		} else {
 			assert.Equal(t, nil, err)
 		}
@@ -501,6 +551,35 @@ func TestServerStreamSad1(t *testing.T) {
 	cancel()
 }
 
+func TestServerStreamCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Fixtures.SetValue("")
+
+	client, err := toldata.NewBus(context.Background(), toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+
+	defer client.Close()
+
+	svc := NewTestServiceToldataClient(client)
+	stream, err := svc.StreamData(ctx, &StreamDataRequest{
+		Id: 2,
+	})
+	assert.Equal(t, nil, err)
+
+	data, err := stream.Receive()
+	if !assert.Equal(t, nil, err) {
+		return
+	}
+	assert.Equal(t, int64(20), data.Data)
+
+	// The client stops reading the stream
+	cancel()
+	_, err = stream.Receive()
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
+
 func getsum(start int64) int64 {
 	data := make([]int64, start)
 	var sum int64
@@ -622,7 +701,7 @@ func TestHealthCheck(t *testing.T) {
 	defer client.Close()
 
 	svc := NewTestServiceToldataClient(client)
-	_, err = svc.ToldataHealthCheck(ctx, &toldata.Empty{})
+	_, err = svc.ToldataHealthCheck(ctx, &Empty{})
 
 	assert.Equal(t, nil, err)
 }
@@ -0,0 +1,62 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+)
+
+type typesService struct{}
+
+func (s *typesService) ToldataHealthCheck(ctx context.Context, req *Empty) (*ToldataHealthCheckInfo, error) {
+	return &ToldataHealthCheckInfo{}, nil
+}
+
+func (s *typesService) Nested(ctx context.Context, req *Outer_Inner) (*Outer_Inner, error) {
+	return &Outer_Inner{Value: "nested:" + req.Value}, nil
+}
+
+func (s *typesService) Now(ctx context.Context, req *EmptyProto) (*Timestamp, error) {
+	return &Timestamp{Seconds: 1234}, nil
+}
+
+func TestTypesService(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+
+	server := NewTypesServiceToldataServer(bus, &typesService{})
+	done, err := server.SubscribeTypesService()
+	assert.Equal(t, nil, err)
+
+	svc := NewTypesServiceToldataClient(bus)
+
+	inner, err := svc.Nested(ctx, &Outer_Inner{Value: "value"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "nested:value", inner.Value)
+
+	now, err := svc.Now(ctx, &EmptyProto{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1234), now.Seconds)
+
+	cancel()
+	<-done
+}
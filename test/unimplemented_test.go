@@ -0,0 +1,87 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/citradigital/toldata"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// partialLegacyService only implements Count
+type partialLegacyService struct {
+	UnimplementedLegacyServiceToldataServer
+}
+
+func (s *partialLegacyService) Count(req *StreamDataRequest, stream LegacyService_CountToldataServer) error {
+	for i := int64(1); i <= req.Id; i++ {
+		err := stream.Send(&StreamDataResponse{Data: i})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestUnimplemented(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+
+	server := NewLegacyServiceToldataServer(bus, &partialLegacyService{})
+	_, err = server.SubscribeLegacyService()
+	assert.Equal(t, nil, err)
+	svc := NewLegacyServiceToldataClient(bus)
+
+	// The default health check reports the service as healthy
+	_, err = svc.ToldataHealthCheck(ctx, &Empty{})
+	assert.Equal(t, nil, err)
+
+	_, err = svc.Echo(ctx, &TestARequest{Input: "hello"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.Equal(t, true, toldata.IsUnimplemented(err))
+	assert.Equal(t, "cdl.toldatatest.LegacyService/Echo is not implemented", status.Convert(err).Message())
+
+	sum, err := svc.Sum(ctx)
+	assert.Equal(t, nil, err)
+	err = sum.Send(&FeedDataRequest{Data: 1})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	count, err := svc.Count(ctx, &StreamDataRequest{Id: 2})
+	assert.Equal(t, nil, err)
+	resp, err := count.Receive()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(1), resp.Data)
+
+	t.Run("REST", func(t *testing.T) {
+		rest, err := NewLegacyServiceREST(ctx, toldata.ServiceConfiguration{URL: natsURL}, toldata.RESTOptions{})
+		assert.Equal(t, nil, err)
+		httpServer := httptest.NewServer(rest.Handler())
+		defer httpServer.Close()
+		httpResp, err := http.Post(httpServer.URL+"/api/cdl.toldatatest/LegacyService/Echo", toldata.ContentTypeJSON, bytes.NewBufferString(`{"input": "rest"}`))
+		assert.Equal(t, nil, err)
+		httpResp.Body.Close()
+		assert.Equal(t, http.StatusNotImplemented, httpResp.StatusCode)
+	})
+}
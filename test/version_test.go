@@ -0,0 +1,182 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/citradigital/toldata"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/stretchr/testify/assert"
+)
+
+// versionService tells which version handled the calls
+type versionService struct {
+	version string
+}
+
+func (s *versionService) ToldataHealthCheck(ctx context.Context, req *Empty) (*ToldataHealthCheckInfo, error) {
+	return &ToldataHealthCheckInfo{Data: s.version}, nil
+}
+
+func (s *versionService) Which(ctx context.Context, req *TestARequest) (*TestAResponse, error) {
+	called, _ := toldata.CalledVersionFromContext(ctx)
+	return &TestAResponse{Output: s.version + ":" + called}, nil
+}
+
+func (s *versionService) Count(req *StreamDataRequest, stream VersionService_CountToldataServer) error {
+	return stream.Send(&StreamDataResponse{Data: map[string]int64{"1.0.0": 1, "2.1.0": 2}[s.version]})
+}
+
+func TestVersions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The version option of the service, then the version of the bus
+	for _, version := range []string{"", "2.1.0"} {
+		bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL, Version: version})
+		assert.Equal(t, nil, err)
+		defer bus.Close()
+		if version == "" {
+			version = "1.0.0"
+		}
+		server := NewVersionServiceToldataServer(bus, &versionService{version: version})
+		server.Discovery = &toldata.DiscoveryOptions{}
+		_, err = server.SubscribeVersionService()
+		assert.Equal(t, nil, err)
+		bus.Connection.Flush()
+	}
+
+	bus, err := toldata.NewBus(ctx, toldata.ServiceConfiguration{URL: natsURL})
+	assert.Equal(t, nil, err)
+	defer bus.Close()
+
+	which := func(svc *VersionServiceToldataClient, ctx context.Context, calls int) map[string]int {
+		outputs := make(map[string]int)
+		for i := 0; i < calls; i++ {
+			resp, err := svc.Which(ctx, &TestARequest{})
+			assert.Equal(t, nil, err)
+			if resp != nil {
+				outputs[resp.Output]++
+			}
+		}
+		return outputs
+	}
+
+	t.Run("Unversioned", func(t *testing.T) {
+		svc := NewVersionServiceToldataClient(bus)
+		outputs := which(svc, ctx, 40)
+		assert.Equal(t, 40, outputs["1.0.0:"]+outputs["2.1.0:"])
+		assert.True(t, outputs["1.0.0:"] > 0)
+		assert.True(t, outputs["2.1.0:"] > 0)
+	})
+
+	t.Run("Constraint", func(t *testing.T) {
+		svc := NewVersionServiceToldataClient(bus)
+		svc.Version = toldata.VersionPolicy{Version: "v2"}
+		assert.Equal(t, map[string]int{"2.1.0:2": 10}, which(svc, ctx, 10))
+
+		svc.Version = toldata.VersionPolicy{Version: "1.0"}
+		assert.Equal(t, map[string]int{"1.0.0:1.0": 10}, which(svc, ctx, 10))
+
+		svc.Version = toldata.VersionPolicy{Version: "2.1.0"}
+		info, err := svc.ToldataHealthCheck(ctx, &Empty{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "2.1.0", info.Data)
+
+		stream, err := svc.Count(ctx, &StreamDataRequest{})
+		assert.Equal(t, nil, err)
+		resp, err := stream.Receive()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, int64(2), resp.Data)
+
+		svc.Version = toldata.VersionPolicy{Version: "3"}
+		_, err = svc.Which(ctx, &TestARequest{})
+		assert.NotEqual(t, nil, err)
+	})
+
+	t.Run("Gather", func(t *testing.T) {
+		svc := NewVersionServiceToldataClient(bus)
+		outputs := func() []string {
+			replies, err := svc.WhichAll(ctx, &TestARequest{}, toldata.GatherOptions{Timeout: 200 * time.Millisecond})
+			assert.Equal(t, nil, err)
+			var outputs []string
+			for _, reply := range replies {
+				assert.Equal(t, nil, reply.Err)
+				outputs = append(outputs, reply.Response.Output)
+			}
+			return outputs
+		}
+		assert.ElementsMatch(t, []string{"1.0.0:", "2.1.0:"}, outputs())
+
+		svc.Version = toldata.VersionPolicy{Version: "2.1"}
+		assert.Equal(t, []string{"2.1.0:2.1"}, outputs())
+	})
+
+	t.Run("Subjects", func(t *testing.T) {
+		// Each constraint is a single token, so that the wildcards of the
+		// dotted schemes match the versioned subjects
+		assert.Equal(t, []string{"svc.Which@1", "svc.Which@1_4", "svc.Which@1_4_2"}, toldata.VersionSubjects("svc.Which", "v1.4.2"))
+	})
+
+	t.Run("WithVersion", func(t *testing.T) {
+		svc := NewVersionServiceToldataClient(bus)
+		svc.Version = toldata.VersionPolicy{Version: "2"}
+		assert.Equal(t, map[string]int{"1.0.0:1": 5}, which(svc, toldata.WithVersion(ctx, "1"), 5))
+	})
+
+	t.Run("Split", func(t *testing.T) {
+		svc := NewVersionServiceToldataClient(bus)
+		svc.Version = toldata.VersionPolicy{Split: []toldata.VersionWeight{
+			{Version: "1", Weight: 80},
+			{Version: "2", Weight: 20},
+		}}
+		outputs := which(svc, ctx, 200)
+		assert.Equal(t, 200, outputs["1.0.0:1"]+outputs["2.1.0:2"])
+		assert.True(t, outputs["1.0.0:1"] > outputs["2.1.0:2"])
+		assert.True(t, outputs["2.1.0:2"] > 0)
+	})
+
+	t.Run("Discovery", func(t *testing.T) {
+		timeout, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		instances, err := toldata.Discover(timeout, bus, "cdl_toldatatest_VersionService")
+		assert.Equal(t, nil, err)
+		var versions []string
+		for _, info := range instances {
+			versions = append(versions, info.Version)
+
+			subject, _ := micro.ControlSubject(micro.StatsVerb, info.Name, info.ID)
+			m, err := bus.Connection.Request(subject, nil, time.Second)
+			assert.Equal(t, nil, err)
+			var stats micro.Stats
+			assert.Equal(t, nil, json.Unmarshal(m.Data, &stats))
+			var data struct {
+				Versions map[string]int `json:"versions"`
+			}
+			assert.Equal(t, nil, json.Unmarshal(stats.Endpoints[0].Data, &data))
+			if info.Version == "2.1.0" {
+				assert.True(t, data.Versions["2"] > 10)
+				assert.Equal(t, 0, data.Versions["2.1.0"])
+			} else {
+				assert.Equal(t, 10, data.Versions["1.0"])
+				assert.True(t, data.Versions["1"] > 5)
+			}
+		}
+		assert.ElementsMatch(t, []string{"1.0.0", "2.1.0"}, versions)
+	})
+}
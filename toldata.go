@@ -15,22 +15,55 @@ package toldata
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
 
 	nats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+	"google.golang.org/grpc/peer"
 )
 
 type ServiceConfiguration struct {
 	URL string
 	ID  string
+	// Options are passed to nats.Connect, e.g. credentials or reconnect settings
+	Options []nats.Option
+	// Retries is how many times calls to idempotent methods are retried
+	// when no server is available
+	Retries int
+	// RetryDelay is the wait between retries, 100ms by default
+	RetryDelay time.Duration
+	// Version is the version served by the servers of the bus, it overrides
+	// the version option of the services
+	Version string
+	// Subjects is the scheme of the subjects of the calls, the legacy one
+	// by default
+	Subjects SubjectScheme
+	// PreviousSubjects is the scheme migrated from, on which the servers
+	// also receive the calls
+	PreviousSubjects *SubjectScheme
+	// Credential is sent by the calls made without one in their context
+	Credential Credential
+	// Verifier authenticates the callers of the servers when set
+	Verifier Verifier
+	// AllowAnonymous lets the calls without credential through when
+	// Verifier is set, they have no principal
+	AllowAnonymous bool
+	// Policy decides the access to the methods of the servers,
+	// DefaultPolicy by default
+	Policy Policy
+	// OnDenied is told of the calls refused by the policy
+	OnDenied func(ctx context.Context, denial Denial)
 }
 
 type Bus struct {
 	Connection    *nats.Conn
 	Configuration ServiceConfiguration
 	Context       context.Context
+
+	instanceID string
 }
 
 func NewBus(ctx context.Context, config ServiceConfiguration) (*Bus, error) {
@@ -45,6 +78,7 @@ func NewBus(ctx context.Context, config ServiceConfiguration) (*Bus, error) {
 	s := &Bus{
 		Configuration: config,
 		Context:       context.WithValue(ctx, k, busID),
+		instanceID:    nuid.Next(),
 	}
 
 	err := s.initConnection()
@@ -55,7 +89,7 @@ func NewBus(ctx context.Context, config ServiceConfiguration) (*Bus, error) {
 }
 
 func (bus *Bus) initConnection() error {
-	nc, err := nats.Connect(bus.Configuration.URL)
+	nc, err := nats.Connect(bus.Configuration.URL, bus.Configuration.Options...)
 	if err != nil {
 		return err
 	}
@@ -64,21 +98,123 @@ func (bus *Bus) initConnection() error {
 	return nil
 }
 
+// Request sends a request to subject and waits for the reply.
+// The caller information attached to ctx is sent along with the request.
+func (bus *Bus) Request(ctx context.Context, subject string, data []byte) (*nats.Msg, error) {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+
+	if p, ok := PeerFromContext(ctx); ok {
+		setPeerHeader(msg.Header, p)
+	}
+	setVersionHeader(ctx, msg.Header)
+	setCredentialHeader(ctx, msg.Header, bus.Configuration.Credential)
+
+	return bus.Connection.RequestMsgWithContext(ctx, msg)
+}
+
+// RequestIdempotent is Request for idempotent methods, the request is sent
+// again while no server is available, up to Configuration.Retries times
+func (bus *Bus) RequestIdempotent(ctx context.Context, subject string, data []byte) (*nats.Msg, error) {
+	var msg *nats.Msg
+	err := bus.retry(ctx, func() error {
+		var err error
+		msg, err = bus.Request(ctx, subject, data)
+		return err
+	})
+	return msg, err
+}
+
+// retry calls fn again while no server is available, up to
+// Configuration.Retries times
+func (bus *Bus) retry(ctx context.Context, fn func() error) error {
+	delay := bus.Configuration.RetryDelay
+	if delay == 0 {
+		delay = 100 * time.Millisecond
+	}
+
+	for i := 0; ; i++ {
+		err := fn()
+		unavailable := errors.Is(err, nats.ErrNoResponders) || errors.Is(err, nats.ErrNoStreamResponse)
+		if !unavailable || i >= bus.Configuration.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Publish sends a request to subject without waiting for any reply
+func (bus *Bus) Publish(ctx context.Context, subject string, data []byte) error {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+
+	if p, ok := PeerFromContext(ctx); ok {
+		setPeerHeader(msg.Header, p)
+	}
+	setVersionHeader(ctx, msg.Header)
+	setCredentialHeader(ctx, msg.Header, bus.Configuration.Credential)
+
+	return bus.Connection.PublishMsg(msg)
+}
+
+// WithDefaultTimeout returns a context with the timeout applied when ctx
+// has no deadline
+func WithDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// CallContext returns the context in which a request received by a server
+// is handled, carrying the caller information sent along with the request
+func (bus *Bus) CallContext(m *nats.Msg) context.Context {
+	ctx := bus.Context
+	if p, ok := peerFromHeader(m.Header); ok {
+		ctx = NewPeerContext(ctx, p)
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: p.Addr})
+	}
+	if constraint := m.Header.Get(HeaderVersion); constraint != "" {
+		ctx = context.WithValue(ctx, calledVersionKey{}, constraint)
+	}
+	if credential, ok := ParseCredential(m.Header.Get(HeaderAuthorization)); ok {
+		ctx = WithCredential(ctx, credential)
+	}
+	return ctx
+}
+
+// InstanceID identifies the bus in the replies, it is Configuration.ID
+// when set and generated otherwise
+func (bus *Bus) InstanceID() string {
+	if bus.Configuration.ID != "" {
+		return bus.Configuration.ID
+	}
+	return bus.instanceID
+}
+
+// Reply sends an encoded reply, tagged with the instance ID of the bus
+func (bus *Bus) Reply(replySubject string, data []byte) error {
+	msg := nats.NewMsg(replySubject)
+	msg.Data = data
+	msg.Header.Set(HeaderBusID, bus.InstanceID())
+	return bus.Connection.PublishMsg(msg)
+}
+
 func (bus *Bus) HandleError(replySubject string, err error) {
 	if replySubject == "" {
 		return
 	}
 
-	now := time.Now()
-	data, errx := proto.Marshal(&ErrorMessage{
-		ErrorMessage: err.Error(),
-		Timestamp:    now.UnixNano(),
-		BusID:        bus.Configuration.ID,
-	})
+	data, errx := proto.Marshal(NewErrorMessage(bus.Configuration.ID, err))
 
 	if errx == nil {
 		one := []byte{1}
-		bus.Connection.Publish(replySubject, append(one, data...))
+		bus.Reply(replySubject, append(one, data...))
 	}
 }
 
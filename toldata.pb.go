@@ -6,6 +6,7 @@ package toldata
 import (
 	fmt "fmt"
 	proto "github.com/gogo/protobuf/proto"
+	types "github.com/gogo/protobuf/types"
 	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	io "io"
 	math "math"
@@ -21,12 +22,16 @@ var _ = math.Inf
 // is compatible with the proto package it is being compiled against.
 // A compilation error at this line likely means your copy of the
 // proto package needs to be updated.
-const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
 type ErrorMessage struct {
 	ErrorMessage string `protobuf:"bytes,1,opt,name=error_message,json=error-message,proto3" json:"error_message,omitempty"`
 	Timestamp    int64  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	BusID        string `protobuf:"bytes,3,opt,name=busID,json=bus-id,proto3" json:"busID,omitempty"`
+	// gRPC status code of the error, 0 when the handler returned a plain error
+	Code int32 `protobuf:"varint,4,opt,name=code,proto3" json:"code,omitempty"`
+	// Details attached to the gRPC status of the error
+	Details []*types.Any `protobuf:"bytes,5,rep,name=details,proto3" json:"details,omitempty"`
 }
 
 func (m *ErrorMessage) Reset()         { *m = ErrorMessage{} }
@@ -83,6 +88,20 @@ func (m *ErrorMessage) GetBusID() string {
 	return ""
 }
 
+func (m *ErrorMessage) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *ErrorMessage) GetDetails() []*types.Any {
+	if m != nil {
+		return m.Details
+	}
+	return nil
+}
+
 type StreamInfo struct {
 	ID string `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
 }
@@ -216,37 +235,196 @@ var E_RestMount = &proto.ExtensionDesc{
 	Filename:      "toldata.proto",
 }
 
+var E_SubjectPrefix = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.ServiceOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         99998,
+	Name:          "cdl.toldata.subject_prefix",
+	Tag:           "bytes,99998,opt,name=subject_prefix",
+	Filename:      "toldata.proto",
+}
+
+var E_QueueGroup = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.ServiceOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         99997,
+	Name:          "cdl.toldata.queue_group",
+	Tag:           "bytes,99997,opt,name=queue_group",
+	Filename:      "toldata.proto",
+}
+
+var E_Version = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.ServiceOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         99996,
+	Name:          "cdl.toldata.version",
+	Tag:           "bytes,99996,opt,name=version",
+	Filename:      "toldata.proto",
+}
+
+var E_Aliases = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.ServiceOptions)(nil),
+	ExtensionType: ([]string)(nil),
+	Field:         99995,
+	Name:          "cdl.toldata.aliases",
+	Tag:           "bytes,99995,rep,name=aliases",
+	Filename:      "toldata.proto",
+}
+
+var E_ServiceScopes = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.ServiceOptions)(nil),
+	ExtensionType: ([]string)(nil),
+	Field:         99994,
+	Name:          "cdl.toldata.service_scopes",
+	Tag:           "bytes,99994,rep,name=service_scopes",
+	Filename:      "toldata.proto",
+}
+
+var E_ServiceRoles = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.ServiceOptions)(nil),
+	ExtensionType: ([]string)(nil),
+	Field:         99993,
+	Name:          "cdl.toldata.service_roles",
+	Tag:           "bytes,99993,rep,name=service_roles",
+	Filename:      "toldata.proto",
+}
+
+var E_DefaultTimeout = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         99999,
+	Name:          "cdl.toldata.default_timeout",
+	Tag:           "bytes,99999,opt,name=default_timeout",
+	Filename:      "toldata.proto",
+}
+
+var E_Idempotent = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         99998,
+	Name:          "cdl.toldata.idempotent",
+	Tag:           "varint,99998,opt,name=idempotent",
+	Filename:      "toldata.proto",
+}
+
+var E_FireAndForget = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         99997,
+	Name:          "cdl.toldata.fire_and_forget",
+	Tag:           "varint,99997,opt,name=fire_and_forget",
+	Filename:      "toldata.proto",
+}
+
+var E_Internal = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         99996,
+	Name:          "cdl.toldata.internal",
+	Tag:           "varint,99996,opt,name=internal",
+	Filename:      "toldata.proto",
+}
+
+var E_Event = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         99995,
+	Name:          "cdl.toldata.event",
+	Tag:           "varint,99995,opt,name=event",
+	Filename:      "toldata.proto",
+}
+
+var E_Durable = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: (*bool)(nil),
+	Field:         99994,
+	Name:          "cdl.toldata.durable",
+	Tag:           "varint,99994,opt,name=durable",
+	Filename:      "toldata.proto",
+}
+
+var E_Scopes = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: ([]string)(nil),
+	Field:         99993,
+	Name:          "cdl.toldata.scopes",
+	Tag:           "bytes,99993,rep,name=scopes",
+	Filename:      "toldata.proto",
+}
+
+var E_Roles = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: ([]string)(nil),
+	Field:         99992,
+	Name:          "cdl.toldata.roles",
+	Tag:           "bytes,99992,rep,name=roles",
+	Filename:      "toldata.proto",
+}
+
 func init() {
 	proto.RegisterType((*ErrorMessage)(nil), "cdl.toldata.ErrorMessage")
 	proto.RegisterType((*StreamInfo)(nil), "cdl.toldata.StreamInfo")
 	proto.RegisterType((*ToldataHealthCheckInfo)(nil), "cdl.toldata.ToldataHealthCheckInfo")
 	proto.RegisterType((*Empty)(nil), "cdl.toldata.Empty")
 	proto.RegisterExtension(E_RestMount)
+	proto.RegisterExtension(E_SubjectPrefix)
+	proto.RegisterExtension(E_QueueGroup)
+	proto.RegisterExtension(E_Version)
+	proto.RegisterExtension(E_Aliases)
+	proto.RegisterExtension(E_ServiceScopes)
+	proto.RegisterExtension(E_ServiceRoles)
+	proto.RegisterExtension(E_DefaultTimeout)
+	proto.RegisterExtension(E_Idempotent)
+	proto.RegisterExtension(E_FireAndForget)
+	proto.RegisterExtension(E_Internal)
+	proto.RegisterExtension(E_Event)
+	proto.RegisterExtension(E_Durable)
+	proto.RegisterExtension(E_Scopes)
+	proto.RegisterExtension(E_Roles)
 }
 
 func init() { proto.RegisterFile("toldata.proto", fileDescriptor_ce427cdc31622079) }
 
 var fileDescriptor_ce427cdc31622079 = []byte{
-	// 301 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x44, 0x90, 0xc1, 0x4e, 0xc2, 0x40,
-	0x10, 0x86, 0x29, 0x08, 0x84, 0x51, 0x3c, 0x6c, 0xa2, 0x69, 0x0c, 0xa9, 0xd8, 0x78, 0xe0, 0x20,
-	0xe5, 0xe0, 0xcd, 0x93, 0x51, 0x48, 0xec, 0x81, 0x98, 0x80, 0x27, 0x2f, 0x64, 0xdb, 0x0e, 0xb0,
-	0xb1, 0xcb, 0x36, 0xbb, 0x53, 0x13, 0x1f, 0xc2, 0xc4, 0x37, 0xf0, 0x75, 0x3c, 0x72, 0xf4, 0x68,
-	0xe0, 0x45, 0x4c, 0xb7, 0x35, 0xde, 0x76, 0xbf, 0xf9, 0xff, 0x3f, 0xf3, 0x0f, 0x74, 0x49, 0xa5,
-	0x09, 0x27, 0x1e, 0x64, 0x5a, 0x91, 0x62, 0x87, 0x71, 0x92, 0x06, 0x15, 0x3a, 0xeb, 0xaf, 0x94,
-	0x5a, 0xa5, 0x38, 0xb2, 0xa3, 0x28, 0x5f, 0x8e, 0x12, 0x34, 0xb1, 0x16, 0x19, 0x29, 0x5d, 0xca,
-	0x7d, 0x01, 0x47, 0x13, 0xad, 0x95, 0x9e, 0xa2, 0x31, 0x7c, 0x85, 0xec, 0x12, 0xba, 0x58, 0xfc,
-	0x17, 0xb2, 0x04, 0xae, 0xd3, 0x77, 0x06, 0x9d, 0x59, 0x09, 0x87, 0x15, 0x64, 0x3d, 0xe8, 0x90,
-	0x90, 0x68, 0x88, 0xcb, 0xcc, 0xad, 0xf7, 0x9d, 0x41, 0x63, 0xf6, 0x0f, 0xd8, 0x09, 0x34, 0xa3,
-	0xdc, 0x84, 0x63, 0xb7, 0x61, 0xbd, 0xad, 0x28, 0x37, 0x43, 0x91, 0xf8, 0x3d, 0x80, 0x39, 0x69,
-	0xe4, 0x32, 0xdc, 0x2c, 0x15, 0x3b, 0x86, 0x7a, 0x38, 0xae, 0xd2, 0xeb, 0xe1, 0xd8, 0xbf, 0x82,
-	0xd3, 0xa7, 0x72, 0xeb, 0x07, 0xe4, 0x29, 0xad, 0xef, 0xd7, 0x18, 0xbf, 0x58, 0x25, 0x83, 0x83,
-	0x02, 0x57, 0x5a, 0xfb, 0xf6, 0xdb, 0xd0, 0x9c, 0xc8, 0x8c, 0xde, 0x6e, 0x6e, 0x01, 0x34, 0x1a,
-	0x5a, 0x48, 0x95, 0x6f, 0x88, 0x9d, 0x07, 0x65, 0xe1, 0xe0, 0xaf, 0x70, 0x30, 0x47, 0xfd, 0x2a,
-	0x62, 0x7c, 0xcc, 0x48, 0xa8, 0x8d, 0x71, 0x3f, 0xdf, 0x5b, 0x36, 0xa5, 0x53, 0x98, 0xa6, 0x85,
-	0xe7, 0xee, 0xe2, 0x6b, 0xe7, 0x39, 0xdb, 0x9d, 0xe7, 0xfc, 0xec, 0x3c, 0xe7, 0x63, 0xef, 0xd5,
-	0xb6, 0x7b, 0xaf, 0xf6, 0xbd, 0xf7, 0x6a, 0xcf, 0xed, 0xea, 0x8c, 0x51, 0xcb, 0xc6, 0x5d, 0xff,
-	0x06, 0x00, 0x00, 0xff, 0xff, 0xe0, 0x57, 0x7f, 0x6c, 0x6b, 0x01, 0x00, 0x00,
+	// 601 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x94, 0x4f, 0x4f, 0x13, 0x4f,
+	0x18, 0xc7, 0x59, 0x4a, 0x5b, 0x78, 0xa0, 0x90, 0x6c, 0x7e, 0x3f, 0x53, 0x09, 0x59, 0x6b, 0xe3,
+	0xa1, 0x07, 0x29, 0x89, 0x26, 0xc6, 0xac, 0x1e, 0x04, 0x01, 0xe9, 0x81, 0x68, 0x0a, 0x27, 0x2f,
+	0x9b, 0xe9, 0xce, 0xd3, 0x32, 0xba, 0xbb, 0xb3, 0xce, 0xcc, 0x12, 0x79, 0x11, 0x24, 0x1e, 0x95,
+	0x3f, 0xfa, 0x0a, 0x7c, 0x1f, 0x1e, 0x39, 0x7a, 0x34, 0xf0, 0x46, 0xcc, 0xfc, 0x59, 0x35, 0x70,
+	0x58, 0x6e, 0xdb, 0xef, 0x7e, 0x3f, 0x9f, 0x3c, 0x9d, 0x79, 0xb2, 0xd0, 0x52, 0x3c, 0xa1, 0x44,
+	0x91, 0x7e, 0x2e, 0xb8, 0xe2, 0xfe, 0x7c, 0x4c, 0x93, 0xbe, 0x8b, 0x96, 0xef, 0x4e, 0x38, 0x9f,
+	0x24, 0xb8, 0x66, 0x5e, 0x8d, 0x8a, 0xf1, 0x1a, 0xc9, 0x8e, 0x6c, 0x6f, 0xb9, 0x73, 0xfd, 0x15,
+	0x45, 0x19, 0x0b, 0x96, 0x2b, 0x2e, 0x6c, 0xa3, 0xfb, 0xdd, 0x83, 0x85, 0x2d, 0x21, 0xb8, 0xd8,
+	0x45, 0x29, 0xc9, 0x04, 0xfd, 0x07, 0xd0, 0x42, 0xfd, 0x3b, 0x4a, 0x6d, 0xd0, 0xf6, 0x3a, 0x5e,
+	0x6f, 0x6e, 0x68, 0xc3, 0x55, 0x17, 0xfa, 0x2b, 0x30, 0xa7, 0x58, 0x8a, 0x52, 0x91, 0x34, 0x6f,
+	0x4f, 0x77, 0xbc, 0x5e, 0x6d, 0xf8, 0x37, 0xf0, 0xff, 0x87, 0xfa, 0xa8, 0x90, 0x83, 0xcd, 0x76,
+	0xcd, 0xb0, 0x8d, 0x51, 0x21, 0x57, 0x19, 0xf5, 0x7d, 0x98, 0x89, 0x39, 0xc5, 0xf6, 0x4c, 0xc7,
+	0xeb, 0xd5, 0x87, 0xe6, 0xd9, 0xef, 0x43, 0x93, 0xa2, 0x22, 0x2c, 0x91, 0xed, 0x7a, 0xa7, 0xd6,
+	0x9b, 0x7f, 0xf4, 0x5f, 0xdf, 0xce, 0xdc, 0x2f, 0x67, 0xee, 0xaf, 0x67, 0x47, 0xc3, 0xb2, 0xd4,
+	0x5d, 0x01, 0xd8, 0x53, 0x02, 0x49, 0x3a, 0xc8, 0xc6, 0xdc, 0x5f, 0x84, 0xe9, 0xc1, 0xa6, 0x9b,
+	0x70, 0x7a, 0xb0, 0xd9, 0x7d, 0x08, 0x77, 0xf6, 0xed, 0xa9, 0xec, 0x20, 0x49, 0xd4, 0xc1, 0xcb,
+	0x03, 0x8c, 0xdf, 0x9b, 0xa6, 0x0f, 0x33, 0x3a, 0x76, 0x5d, 0xf3, 0xdc, 0x6d, 0x42, 0x7d, 0x2b,
+	0xcd, 0xd5, 0x51, 0xf8, 0x02, 0x40, 0xa0, 0x54, 0x51, 0xca, 0x8b, 0x4c, 0xf9, 0xf7, 0x6e, 0x4c,
+	0xb0, 0x87, 0xe2, 0x90, 0xc5, 0xf8, 0x3a, 0x57, 0x8c, 0x67, 0xb2, 0xfd, 0xed, 0xb8, 0x61, 0x2c,
+	0x73, 0x1a, 0xda, 0xd5, 0x4c, 0xb8, 0x03, 0x8b, 0xb2, 0x18, 0xbd, 0xc3, 0x58, 0x45, 0xb9, 0xc0,
+	0x31, 0xfb, 0x58, 0x6d, 0xf9, 0xea, 0x2c, 0x2d, 0x07, 0xbe, 0x31, 0x5c, 0xb8, 0x01, 0xf3, 0x1f,
+	0x0a, 0x2c, 0x30, 0x9a, 0x08, 0x5e, 0xe4, 0xd5, 0x9a, 0x73, 0xa7, 0x01, 0x43, 0xbd, 0xd2, 0x50,
+	0xf8, 0x0c, 0x9a, 0x87, 0x28, 0x24, 0xe3, 0x59, 0x35, 0x7f, 0xe6, 0xf8, 0x92, 0xd0, 0x30, 0x49,
+	0x18, 0x91, 0x28, 0xab, 0xe1, 0xd3, 0xe3, 0x46, 0xa7, 0xa6, 0x61, 0x47, 0x98, 0x73, 0xb0, 0x95,
+	0x48, 0xc6, 0x3c, 0xbf, 0x8d, 0xe3, 0xc4, 0x39, 0x5a, 0x0e, 0xdc, 0x33, 0x5c, 0xb8, 0x0d, 0x65,
+	0x10, 0x09, 0x9e, 0xdc, 0x46, 0xf4, 0xc5, 0x89, 0x16, 0x1c, 0x37, 0xd4, 0x58, 0x38, 0x80, 0x25,
+	0x8a, 0x63, 0x52, 0x24, 0x2a, 0xd2, 0x0b, 0xca, 0x0b, 0xe5, 0x07, 0x37, 0x4c, 0xbb, 0xa8, 0x0e,
+	0x38, 0xbd, 0x7e, 0xbf, 0x8b, 0x0e, 0xdc, 0xb7, 0x9c, 0x5e, 0x13, 0x46, 0x31, 0xcd, 0xb9, 0xc2,
+	0xac, 0xda, 0x62, 0xef, 0x77, 0x76, 0xf8, 0x0f, 0x13, 0xee, 0xc0, 0xd2, 0x98, 0x09, 0x8c, 0x48,
+	0x46, 0xa3, 0x31, 0x17, 0x13, 0xac, 0xd6, 0x9c, 0x3b, 0x4d, 0x4b, 0x83, 0xeb, 0x19, 0xdd, 0x36,
+	0x58, 0xf8, 0x1c, 0x66, 0x59, 0xa6, 0x50, 0x64, 0x24, 0xa9, 0x54, 0x9c, 0x39, 0xc5, 0x1f, 0x22,
+	0x7c, 0x02, 0x75, 0x3c, 0xbc, 0xcd, 0x9f, 0x38, 0x75, 0xa8, 0xad, 0x87, 0x21, 0x34, 0x69, 0x21,
+	0xc8, 0x28, 0xc1, 0x4a, 0xf2, 0xc4, 0x91, 0x25, 0x10, 0x3e, 0x85, 0x86, 0x5b, 0x89, 0x2a, 0xb4,
+	0xbc, 0x48, 0xd7, 0xd7, 0xd3, 0xda, 0x15, 0xa8, 0x02, 0x3f, 0x3b, 0xd0, 0xd6, 0x37, 0xee, 0xff,
+	0xb8, 0x0c, 0xbc, 0x8b, 0xcb, 0xc0, 0xfb, 0x75, 0x19, 0x78, 0x9f, 0xae, 0x82, 0xa9, 0x8b, 0xab,
+	0x60, 0xea, 0xe7, 0x55, 0x30, 0xf5, 0xb6, 0xe9, 0xbe, 0x9d, 0xa3, 0x86, 0x31, 0x3d, 0xfe, 0x3d,
+	0x00, 0xa3, 0x1f, 0x88, 0x14, 0x60, 0x05, 0x00, 0x00,
 }
 
 func (m *ErrorMessage) Marshal() (dAtA []byte, err error) {
@@ -269,6 +447,25 @@ func (m *ErrorMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Details) > 0 {
+		for iNdEx := len(m.Details) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Details[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintToldata(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x2a
+		}
+	}
+	if m.Code != 0 {
+		i = encodeVarintToldata(dAtA, i, uint64(m.Code))
+		i--
+		dAtA[i] = 0x20
+	}
 	if len(m.BusID) > 0 {
 		i -= len(m.BusID)
 		copy(dAtA[i:], m.BusID)
@@ -402,6 +599,15 @@ func (m *ErrorMessage) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovToldata(uint64(l))
 	}
+	if m.Code != 0 {
+		n += 1 + sovToldata(uint64(m.Code))
+	}
+	if len(m.Details) > 0 {
+		for _, e := range m.Details {
+			l = e.Size()
+			n += 1 + l + sovToldata(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -558,6 +764,59 @@ func (m *ErrorMessage) Unmarshal(dAtA []byte) error {
 			}
 			m.BusID = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowToldata
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Details", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowToldata
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthToldata
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthToldata
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Details = append(m.Details, &types.Any{})
+			if err := m.Details[len(m.Details)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipToldata(dAtA[iNdEx:])
@@ -0,0 +1,36 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package toldatapb holds the messages of toldata.proto generated by
+// protoc-gen-go, for code generated with messages=golang.
+package toldatapb
+
+import (
+	"github.com/citradigital/toldata"
+	gogotypes "github.com/gogo/protobuf/types"
+)
+
+// Err converts an ErrorMessage received from a server into an error,
+// see toldata.ErrorMessage.Err
+func (m *ErrorMessage) Err() error {
+	msg := &toldata.ErrorMessage{
+		ErrorMessage: m.GetErrorMessage(),
+		Timestamp:    m.GetTimestamp(),
+		BusID:        m.GetBusID(),
+		Code:         m.GetCode(),
+	}
+	for _, detail := range m.GetDetails() {
+		msg.Details = append(msg.Details, &gogotypes.Any{TypeUrl: detail.GetTypeUrl(), Value: detail.GetValue()})
+	}
+	return msg.Err()
+}
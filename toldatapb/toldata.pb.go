@@ -0,0 +1,671 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: github.com/citradigital/toldata/toldata.proto
+
+package toldatapb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ErrorMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ErrorMessage string `protobuf:"bytes,1,opt,name=error_message,json=error-message,proto3" json:"error_message,omitempty"`
+	Timestamp    int64  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	BusID        string `protobuf:"bytes,3,opt,name=busID,json=bus-id,proto3" json:"busID,omitempty"`
+	// gRPC status code of the error, 0 when the handler returned a plain error
+	Code int32 `protobuf:"varint,4,opt,name=code,proto3" json:"code,omitempty"`
+	// Details attached to the gRPC status of the error
+	Details []*anypb.Any `protobuf:"bytes,5,rep,name=details,proto3" json:"details,omitempty"`
+}
+
+func (x *ErrorMessage) Reset() {
+	*x = ErrorMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_citradigital_toldata_toldata_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ErrorMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorMessage) ProtoMessage() {}
+
+func (x *ErrorMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_citradigital_toldata_toldata_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorMessage.ProtoReflect.Descriptor instead.
+func (*ErrorMessage) Descriptor() ([]byte, []int) {
+	return file_github_com_citradigital_toldata_toldata_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ErrorMessage) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *ErrorMessage) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ErrorMessage) GetBusID() string {
+	if x != nil {
+		return x.BusID
+	}
+	return ""
+}
+
+func (x *ErrorMessage) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ErrorMessage) GetDetails() []*anypb.Any {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+type StreamInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ID string `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+}
+
+func (x *StreamInfo) Reset() {
+	*x = StreamInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_citradigital_toldata_toldata_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamInfo) ProtoMessage() {}
+
+func (x *StreamInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_citradigital_toldata_toldata_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamInfo.ProtoReflect.Descriptor instead.
+func (*StreamInfo) Descriptor() ([]byte, []int) {
+	return file_github_com_citradigital_toldata_toldata_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StreamInfo) GetID() string {
+	if x != nil {
+		return x.ID
+	}
+	return ""
+}
+
+type ToldataHealthCheckInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data string `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *ToldataHealthCheckInfo) Reset() {
+	*x = ToldataHealthCheckInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_citradigital_toldata_toldata_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ToldataHealthCheckInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToldataHealthCheckInfo) ProtoMessage() {}
+
+func (x *ToldataHealthCheckInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_citradigital_toldata_toldata_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToldataHealthCheckInfo.ProtoReflect.Descriptor instead.
+func (*ToldataHealthCheckInfo) Descriptor() ([]byte, []int) {
+	return file_github_com_citradigital_toldata_toldata_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ToldataHealthCheckInfo) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+type Empty struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_github_com_citradigital_toldata_toldata_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_citradigital_toldata_toldata_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_github_com_citradigital_toldata_toldata_proto_rawDescGZIP(), []int{3}
+}
+
+var file_github_com_citradigital_toldata_toldata_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         99999,
+		Name:          "cdl.toldata.rest_mount",
+		Tag:           "bytes,99999,opt,name=rest_mount",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         99998,
+		Name:          "cdl.toldata.subject_prefix",
+		Tag:           "bytes,99998,opt,name=subject_prefix",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         99997,
+		Name:          "cdl.toldata.queue_group",
+		Tag:           "bytes,99997,opt,name=queue_group",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         99996,
+		Name:          "cdl.toldata.version",
+		Tag:           "bytes,99996,opt,name=version",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         99995,
+		Name:          "cdl.toldata.aliases",
+		Tag:           "bytes,99995,rep,name=aliases",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         99994,
+		Name:          "cdl.toldata.service_scopes",
+		Tag:           "bytes,99994,rep,name=service_scopes",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         99993,
+		Name:          "cdl.toldata.service_roles",
+		Tag:           "bytes,99993,rep,name=service_roles",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         99999,
+		Name:          "cdl.toldata.default_timeout",
+		Tag:           "bytes,99999,opt,name=default_timeout",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         99998,
+		Name:          "cdl.toldata.idempotent",
+		Tag:           "varint,99998,opt,name=idempotent",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         99997,
+		Name:          "cdl.toldata.fire_and_forget",
+		Tag:           "varint,99997,opt,name=fire_and_forget",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         99996,
+		Name:          "cdl.toldata.internal",
+		Tag:           "varint,99996,opt,name=internal",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         99995,
+		Name:          "cdl.toldata.event",
+		Tag:           "varint,99995,opt,name=event",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         99994,
+		Name:          "cdl.toldata.durable",
+		Tag:           "varint,99994,opt,name=durable",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         99993,
+		Name:          "cdl.toldata.scopes",
+		Tag:           "bytes,99993,rep,name=scopes",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         99992,
+		Name:          "cdl.toldata.roles",
+		Tag:           "bytes,99992,rep,name=roles",
+		Filename:      "github.com/citradigital/toldata/toldata.proto",
+	},
+}
+
+// Extension fields to descriptorpb.ServiceOptions.
+var (
+	// Path prefix of the REST gateway routes, /api by default
+	//
+	// optional string rest_mount = 99999;
+	E_RestMount = &file_github_com_citradigital_toldata_toldata_proto_extTypes[0]
+	// Prepended to the NATS subjects of the service, as <prefix>/<package>/<Service>/<Method>
+	//
+	// optional string subject_prefix = 99998;
+	E_SubjectPrefix = &file_github_com_citradigital_toldata_toldata_proto_extTypes[1]
+	// Queue group of the servers, the subject of the service by default
+	//
+	// optional string queue_group = 99997;
+	E_QueueGroup = &file_github_com_citradigital_toldata_toldata_proto_extTypes[2]
+	// Version served by the servers, overridden by ServiceConfiguration.Version.
+	// The servers also receive the calls on the subjects of this version.
+	//
+	// optional string version = 99996;
+	E_Version = &file_github_com_citradigital_toldata_toldata_proto_extTypes[3]
+	// Former fully qualified names of the service, such as "pkg.OldService".
+	// The servers also receive the calls on their subjects, with the same prefix.
+	//
+	// repeated string aliases = 99995;
+	E_Aliases = &file_github_com_citradigital_toldata_toldata_proto_extTypes[4]
+	// Scopes required by every method of the service, along with their own
+	//
+	// repeated string service_scopes = 99994;
+	E_ServiceScopes = &file_github_com_citradigital_toldata_toldata_proto_extTypes[5]
+	// Roles allowed to call the methods without roles option, one is required
+	//
+	// repeated string service_roles = 99993;
+	E_ServiceRoles = &file_github_com_citradigital_toldata_toldata_proto_extTypes[6]
+)
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// Deadline of calls made without one, as a Go duration such as "5s"
+	//
+	// optional string default_timeout = 99999;
+	E_DefaultTimeout = &file_github_com_citradigital_toldata_toldata_proto_extTypes[7]
+	// Calls which found no server are retried up to ServiceConfiguration.Retries times
+	//
+	// optional bool idempotent = 99998;
+	E_Idempotent = &file_github_com_citradigital_toldata_toldata_proto_extTypes[8]
+	// The client publishes the request without waiting for the reply
+	//
+	// optional bool fire_and_forget = 99997;
+	E_FireAndForget = &file_github_com_citradigital_toldata_toldata_proto_extTypes[9]
+	// The method is only served on the bus, gateways do not expose it
+	//
+	// optional bool internal = 99996;
+	E_Internal = &file_github_com_citradigital_toldata_toldata_proto_extTypes[10]
+	// The method publishes events, clients get Publish<Method> and servers receive
+	// them in their queue group or all of them, see toldata.EventDelivery
+	//
+	// optional bool event = 99995;
+	E_Event = &file_github_com_citradigital_toldata_toldata_proto_extTypes[11]
+	// Calls are stored in a JetStream stream and consumed by the servers until
+	// they succeed, see toldata.DurableOptions
+	//
+	// optional bool durable = 99994;
+	E_Durable = &file_github_com_citradigital_toldata_toldata_proto_extTypes[12]
+	// Scopes the caller must all have, see toldata.Policy
+	//
+	// repeated string scopes = 99993;
+	E_Scopes = &file_github_com_citradigital_toldata_toldata_proto_extTypes[13]
+	// Roles allowed to call the method, the caller must have one of them
+	//
+	// repeated string roles = 99992;
+	E_Roles = &file_github_com_citradigital_toldata_toldata_proto_extTypes[14]
+)
+
+var File_github_com_citradigital_toldata_toldata_proto protoreflect.FileDescriptor
+
+var file_github_com_citradigital_toldata_toldata_proto_rawDesc = []byte{
+	0x0a, 0x2d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x69, 0x74,
+	0x72, 0x61, 0x64, 0x69, 0x67, 0x69, 0x74, 0x61, 0x6c, 0x2f, 0x74, 0x6f, 0x6c, 0x64, 0x61, 0x74,
+	0x61, 0x2f, 0x74, 0x6f, 0x6c, 0x64, 0x61, 0x74, 0x61, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x0b, 0x63, 0x64, 0x6c, 0x2e, 0x74, 0x6f, 0x6c, 0x64, 0x61, 0x74, 0x61, 0x1a, 0x19, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x61, 0x6e,
+	0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xad, 0x01, 0x0a, 0x0c, 0x45, 0x72,
+	0x72, 0x6f, 0x72, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x24, 0x0a, 0x0d, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0d, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x2d, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
+	0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x15,
+	0x0a, 0x05, 0x62, 0x75, 0x73, 0x49, 0x44, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x62,
+	0x75, 0x73, 0x2d, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x2e, 0x0a, 0x07, 0x64, 0x65, 0x74,
+	0x61, 0x69, 0x6c, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e, 0x79,
+	0x52, 0x07, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x22, 0x1c, 0x0a, 0x0a, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x02, 0x49, 0x44, 0x22, 0x2c, 0x0a, 0x16, 0x54, 0x6f, 0x6c, 0x64, 0x61,
+	0x74, 0x61, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x49, 0x6e, 0x66,
+	0x6f, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x3a, 0x40,
+	0x0a, 0x0a, 0x72, 0x65, 0x73, 0x74, 0x5f, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1f, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x9f, 0x8d,
+	0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x73, 0x74, 0x4d, 0x6f, 0x75, 0x6e, 0x74,
+	0x3a, 0x48, 0x0a, 0x0e, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x70, 0x72, 0x65, 0x66,
+	0x69, 0x78, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x18, 0x9e, 0x8d, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x75, 0x62,
+	0x6a, 0x65, 0x63, 0x74, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x3a, 0x42, 0x0a, 0x0b, 0x71, 0x75,
+	0x65, 0x75, 0x65, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x9d, 0x8d, 0x06, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x71, 0x75, 0x65, 0x75, 0x65, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x3a, 0x3b,
+	0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x9c, 0x8d, 0x06, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x3a, 0x3b, 0x0a, 0x07, 0x61,
+	0x6c, 0x69, 0x61, 0x73, 0x65, 0x73, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x9b, 0x8d, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x07, 0x61, 0x6c, 0x69, 0x61, 0x73, 0x65, 0x73, 0x3a, 0x48, 0x0a, 0x0e, 0x73, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x5f, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x9a, 0x8d, 0x06, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53, 0x63, 0x6f, 0x70,
+	0x65, 0x73, 0x3a, 0x46, 0x0a, 0x0d, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x72, 0x6f,
+	0x6c, 0x65, 0x73, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4f, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0x99, 0x8d, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x52, 0x6f, 0x6c, 0x65, 0x73, 0x3a, 0x49, 0x0a, 0x0f, 0x64, 0x65,
+	0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x12, 0x1e, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x9f, 0x8d,
+	0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x54, 0x69,
+	0x6d, 0x65, 0x6f, 0x75, 0x74, 0x3a, 0x40, 0x0a, 0x0a, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74,
+	0x65, 0x6e, 0x74, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x18, 0x9e, 0x8d, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69, 0x64, 0x65,
+	0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x74, 0x3a, 0x48, 0x0a, 0x0f, 0x66, 0x69, 0x72, 0x65, 0x5f,
+	0x61, 0x6e, 0x64, 0x5f, 0x66, 0x6f, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74,
+	0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x9d, 0x8d, 0x06, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x0d, 0x66, 0x69, 0x72, 0x65, 0x41, 0x6e, 0x64, 0x46, 0x6f, 0x72, 0x67, 0x65,
+	0x74, 0x3a, 0x3c, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x12, 0x1e, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x9c, 0x8d,
+	0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x3a,
+	0x36, 0x0a, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f,
+	0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x9b, 0x8d, 0x06, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x3a, 0x3a, 0x0a, 0x07, 0x64, 0x75, 0x72, 0x61, 0x62,
+	0x6c, 0x65, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x18, 0x9a, 0x8d, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x64, 0x75, 0x72, 0x61,
+	0x62, 0x6c, 0x65, 0x3a, 0x38, 0x0a, 0x06, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x12, 0x1e, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x99, 0x8d,
+	0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x3a, 0x36, 0x0a,
+	0x05, 0x72, 0x6f, 0x6c, 0x65, 0x73, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x98, 0x8d, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05,
+	0x72, 0x6f, 0x6c, 0x65, 0x73, 0x42, 0x09, 0x5a, 0x07, 0x74, 0x6f, 0x6c, 0x64, 0x61, 0x74, 0x61,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_github_com_citradigital_toldata_toldata_proto_rawDescOnce sync.Once
+	file_github_com_citradigital_toldata_toldata_proto_rawDescData = file_github_com_citradigital_toldata_toldata_proto_rawDesc
+)
+
+func file_github_com_citradigital_toldata_toldata_proto_rawDescGZIP() []byte {
+	file_github_com_citradigital_toldata_toldata_proto_rawDescOnce.Do(func() {
+		file_github_com_citradigital_toldata_toldata_proto_rawDescData = protoimpl.X.CompressGZIP(file_github_com_citradigital_toldata_toldata_proto_rawDescData)
+	})
+	return file_github_com_citradigital_toldata_toldata_proto_rawDescData
+}
+
+var file_github_com_citradigital_toldata_toldata_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_github_com_citradigital_toldata_toldata_proto_goTypes = []interface{}{
+	(*ErrorMessage)(nil),                // 0: cdl.toldata.ErrorMessage
+	(*StreamInfo)(nil),                  // 1: cdl.toldata.StreamInfo
+	(*ToldataHealthCheckInfo)(nil),      // 2: cdl.toldata.ToldataHealthCheckInfo
+	(*Empty)(nil),                       // 3: cdl.toldata.Empty
+	(*anypb.Any)(nil),                   // 4: google.protobuf.Any
+	(*descriptorpb.ServiceOptions)(nil), // 5: google.protobuf.ServiceOptions
+	(*descriptorpb.MethodOptions)(nil),  // 6: google.protobuf.MethodOptions
+}
+var file_github_com_citradigital_toldata_toldata_proto_depIdxs = []int32{
+	4,  // 0: cdl.toldata.ErrorMessage.details:type_name -> google.protobuf.Any
+	5,  // 1: cdl.toldata.rest_mount:extendee -> google.protobuf.ServiceOptions
+	5,  // 2: cdl.toldata.subject_prefix:extendee -> google.protobuf.ServiceOptions
+	5,  // 3: cdl.toldata.queue_group:extendee -> google.protobuf.ServiceOptions
+	5,  // 4: cdl.toldata.version:extendee -> google.protobuf.ServiceOptions
+	5,  // 5: cdl.toldata.aliases:extendee -> google.protobuf.ServiceOptions
+	5,  // 6: cdl.toldata.service_scopes:extendee -> google.protobuf.ServiceOptions
+	5,  // 7: cdl.toldata.service_roles:extendee -> google.protobuf.ServiceOptions
+	6,  // 8: cdl.toldata.default_timeout:extendee -> google.protobuf.MethodOptions
+	6,  // 9: cdl.toldata.idempotent:extendee -> google.protobuf.MethodOptions
+	6,  // 10: cdl.toldata.fire_and_forget:extendee -> google.protobuf.MethodOptions
+	6,  // 11: cdl.toldata.internal:extendee -> google.protobuf.MethodOptions
+	6,  // 12: cdl.toldata.event:extendee -> google.protobuf.MethodOptions
+	6,  // 13: cdl.toldata.durable:extendee -> google.protobuf.MethodOptions
+	6,  // 14: cdl.toldata.scopes:extendee -> google.protobuf.MethodOptions
+	6,  // 15: cdl.toldata.roles:extendee -> google.protobuf.MethodOptions
+	16, // [16:16] is the sub-list for method output_type
+	16, // [16:16] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	1,  // [1:16] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_github_com_citradigital_toldata_toldata_proto_init() }
+func file_github_com_citradigital_toldata_toldata_proto_init() {
+	if File_github_com_citradigital_toldata_toldata_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_github_com_citradigital_toldata_toldata_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ErrorMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_citradigital_toldata_toldata_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_citradigital_toldata_toldata_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ToldataHealthCheckInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_github_com_citradigital_toldata_toldata_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Empty); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_github_com_citradigital_toldata_toldata_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 15,
+			NumServices:   0,
+		},
+		GoTypes:           file_github_com_citradigital_toldata_toldata_proto_goTypes,
+		DependencyIndexes: file_github_com_citradigital_toldata_toldata_proto_depIdxs,
+		MessageInfos:      file_github_com_citradigital_toldata_toldata_proto_msgTypes,
+		ExtensionInfos:    file_github_com_citradigital_toldata_toldata_proto_extTypes,
+	}.Build()
+	File_github_com_citradigital_toldata_toldata_proto = out.File
+	file_github_com_citradigital_toldata_toldata_proto_rawDesc = nil
+	file_github_com_citradigital_toldata_toldata_proto_goTypes = nil
+	file_github_com_citradigital_toldata_toldata_proto_depIdxs = nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 Citra Digital Lintas
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toldata
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+// HeaderVersion carries the version constraint selected by the client
+const HeaderVersion = "Toldata-Version"
+
+// VersionSubject returns the subject of a method served by the versions
+// matching constraint. The constraint is a single token, its dots are
+// replaced by underscores: 1.4 is served on <subject>@1_4.
+func VersionSubject(subject, constraint string) string {
+	return subject + "@" + natsName(normalizeVersion(constraint))
+}
+
+// VersionSubjects returns the versioned subjects on which the servers of a
+// version receive the calls, one per constraint matching it: 1.4.2 is
+// served for 1, 1.4 and 1.4.2
+func VersionSubjects(subject, version string) []string {
+	version = normalizeVersion(version)
+	if version == "" {
+		return nil
+	}
+	var subjects []string
+	parts := strings.Split(version, ".")
+	for i := range parts {
+		subjects = append(subjects, VersionSubject(subject, strings.Join(parts[:i+1], ".")))
+	}
+	return subjects
+}
+
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(version, "v"), "V")
+}
+
+// ServiceVersion returns the version served by the servers of the bus,
+// Configuration.Version when set and the version option otherwise
+func (bus *Bus) ServiceVersion(version string) string {
+	if bus.Configuration.Version != "" {
+		return bus.Configuration.Version
+	}
+	return version
+}
+
+// VersionWeight is the share of the calls sent to the versions matching
+// a constraint
+type VersionWeight struct {
+	Version string
+	Weight  int
+}
+
+// VersionPolicy selects the versions of the servers called by a client.
+// A constraint is a version or a prefix of it, such as 1 or 1.4. The
+// durable calls are not versioned, they are stored in the stream of the
+// service whichever version handles them.
+type VersionPolicy struct {
+	// Version is the constraint of the calls, any version when empty
+	Version string
+	// Split shares the calls between constraints by weight, it is used
+	// instead of Version when set
+	Split []VersionWeight
+}
+
+// Select returns the constraint of a call
+func (p VersionPolicy) Select() string {
+	total := 0
+	for _, w := range p.Split {
+		if w.Weight > 0 {
+			total += w.Weight
+		}
+	}
+	if total == 0 {
+		return p.Version
+	}
+
+	n := rand.Intn(total)
+	for _, w := range p.Split {
+		if w.Weight <= 0 {
+			continue
+		}
+		if n < w.Weight {
+			return w.Version
+		}
+		n -= w.Weight
+	}
+	return p.Version
+}
+
+// Route returns the subject of a call to a method and the context sending
+// the selected constraint along with it. The constraint set on ctx with
+// WithVersion is used before the policy.
+func (p VersionPolicy) Route(ctx context.Context, subject string) (context.Context, string) {
+	constraint, ok := ctx.Value(versionKey{}).(string)
+	if !ok {
+		constraint = p.Select()
+	}
+	if constraint == "" {
+		return ctx, subject
+	}
+	return context.WithValue(ctx, selectedVersionKey{}, normalizeVersion(constraint)), VersionSubject(subject, constraint)
+}
+
+type versionKey struct{}
+type selectedVersionKey struct{}
+type calledVersionKey struct{}
+
+// WithVersion sets the version constraint of the calls made with ctx, the
+// empty constraint calls any version
+func WithVersion(ctx context.Context, constraint string) context.Context {
+	return context.WithValue(ctx, versionKey{}, constraint)
+}
+
+// CalledVersionFromContext returns the version constraint selected by the
+// client of a call, in the context of the server handling it
+func CalledVersionFromContext(ctx context.Context) (string, bool) {
+	constraint, ok := ctx.Value(calledVersionKey{}).(string)
+	return constraint, ok
+}
+
+func setVersionHeader(ctx context.Context, header nats.Header) {
+	if constraint, ok := ctx.Value(selectedVersionKey{}).(string); ok {
+		header.Set(HeaderVersion, constraint)
+	}
+}